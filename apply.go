@@ -0,0 +1,144 @@
+package gpt
+
+import "fmt"
+
+// ChangeKind - kind of PartitionChange
+type ChangeKind int
+
+const (
+	ChangeCreated     ChangeKind = iota // Partition was added
+	ChangeResized                       // LastLBA of partition was changed
+	ChangeTypeChanged                   // Type of partition, found by name, was changed
+)
+
+func (this ChangeKind) String() string {
+	switch this {
+	case ChangeCreated:
+		return "ChangeCreated"
+	case ChangeResized:
+		return "ChangeResized"
+	case ChangeTypeChanged:
+		return "ChangeTypeChanged"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(this))
+	}
+}
+
+// PartitionChange - change of the table, made by Apply
+type PartitionChange struct {
+	Kind           ChangeKind
+	Index          int
+	OldSizeSectors uint64 // Zero for created partition
+	NewSizeSectors uint64
+}
+
+// Apply - change the table for match specs. Partition for spec is found by name, or by type if spec has no name.
+// Missed partitions are added, partitions with other size are resized from their FirstLBA, Rest partition is expanded to
+// next partition. Partitions, which match specs, and partitions without specs aren't changed, so second Apply with same
+// specs does nothing. Rest spec is applied after other specs. The table isn't changed on error.
+func (this *Table) Apply(specs []PartitionSpec) ([]PartitionChange, error) {
+	res := this.copy()
+	var changes []PartitionChange
+
+	var ordered []PartitionSpec
+	for _, spec := range specs {
+		if !spec.Rest {
+			ordered = append(ordered, spec)
+		}
+	}
+	for _, spec := range specs {
+		if spec.Rest {
+			ordered = append(ordered, spec)
+		}
+	}
+
+	for _, spec := range ordered {
+		var err error
+		var specChanges []PartitionChange
+		index := res.findSpecPartition(spec)
+		if index == -1 {
+			specChanges, err = res.applyCreate(spec)
+		} else {
+			specChanges, err = res.applyUpdate(index, spec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spec %q: %w", spec.Name, err)
+		}
+		changes = append(changes, specChanges...)
+	}
+
+	*this = res
+	return changes, nil
+}
+
+// Index of partition for spec or -1
+func (this Table) findSpecPartition(spec PartitionSpec) int {
+	for i, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		if spec.Name != "" && p.Name() == spec.Name || spec.Name == "" && p.Type == spec.Type {
+			return i
+		}
+	}
+	return -1
+}
+
+func (this *Table) applyCreate(spec PartitionSpec) ([]PartitionChange, error) {
+	sizeBytes := spec.SizeBytes
+	if spec.Rest {
+		// Largest free space with aligned start
+		alignment := spec.AlignmentSectors
+		if alignment == 0 {
+			alignment = RecommendedAlignment(spec.Type, this.SectorSize)
+		}
+		var maxSectors uint64
+		for _, region := range this.FreeRegions() {
+			start := alignUp(region.FirstLBA, alignment)
+			if start >= region.FirstLBA && start <= region.LastLBA && region.LastLBA-start+1 > maxSectors {
+				maxSectors = region.LastLBA - start + 1
+			}
+		}
+		sizeBytes = maxSectors * this.SectorSize
+	}
+
+	index, err := this.AddPartition(spec.Type, sizeBytes, spec.AlignmentSectors, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	return []PartitionChange{{Kind: ChangeCreated, Index: index, NewSizeSectors: this.Partitions[index].SizeInSectors()}}, nil
+}
+
+func (this *Table) applyUpdate(index int, spec PartitionSpec) ([]PartitionChange, error) {
+	var changes []PartitionChange
+	p := this.Partitions[index]
+	if p.Type != spec.Type {
+		this.Partitions[index].Type = spec.Type
+		this.Touch()
+		changes = append(changes, PartitionChange{Kind: ChangeTypeChanged, Index: index,
+			OldSizeSectors: p.SizeInSectors(), NewSizeSectors: p.SizeInSectors()})
+	}
+
+	if spec.Rest {
+		if err := this.ExpandToFill(index); err != nil {
+			return nil, err
+		}
+	} else if sizeSectors := (spec.SizeBytes + this.SectorSize - 1) / this.SectorSize; sizeSectors != p.SizeInSectors() {
+		resized := this.Partitions[index]
+		resized.LastLBA = resized.FirstLBA + sizeSectors - 1
+		if sizeSectors == 0 || resized.LastLBA < resized.FirstLBA {
+			return nil, fmt.Errorf("%w: bad size %v bytes", ErrNoSpace, spec.SizeBytes)
+		}
+		if err := this.checkPlace(resized, index); err != nil {
+			return nil, err
+		}
+		this.Partitions[index] = resized
+		this.Touch()
+	}
+
+	if newSize := this.Partitions[index].SizeInSectors(); newSize != p.SizeInSectors() {
+		changes = append(changes, PartitionChange{Kind: ChangeResized, Index: index,
+			OldSizeSectors: p.SizeInSectors(), NewSizeSectors: newSize})
+	}
+	return changes, nil
+}
@@ -0,0 +1,56 @@
+package gpt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	table := NewTable(1024*1024*100, nil)
+	specs := []PartitionSpec{
+		{Rest: true, Type: GUID_LINUX_FS, Name: "root"},
+		{SizeBytes: 1024 * 1024 * 10, Type: GUID_EFI_SYSTEM, Name: "esp"},
+	}
+
+	changes, err := table.Apply(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PartitionChange{
+		{Kind: ChangeCreated, Index: 0, NewSizeSectors: 20480},
+		{Kind: ChangeCreated, Index: 1, NewSizeSectors: table.Header.LastUsableLBA - 22528 + 1},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Error(changes)
+	}
+	if table.Partitions[0].Name() != "esp" || table.Partitions[1].Name() != "root" || table.Partitions[1].FirstLBA != 22528 {
+		t.Error("Bad partitions")
+	}
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// Idempotent
+	before := table.copy()
+	if changes, err = table.Apply(specs); err != nil || len(changes) != 0 {
+		t.Error(changes, err)
+	}
+	if !reflect.DeepEqual(before, table) {
+		t.Error("Table changed by second apply")
+	}
+
+	// Shrink root, ESP can't grow over it
+	specs[0] = PartitionSpec{SizeBytes: 1024 * 1024 * 50, Type: GUID_LINUX_FS, Name: "root"}
+	changes, err = table.Apply(specs)
+	if err != nil || len(changes) != 1 || changes[0].Kind != ChangeResized || changes[0].NewSizeSectors != 102400 {
+		t.Error(changes, err)
+	}
+	specs[1].SizeBytes *= 2
+	if _, err = table.Apply(specs); !errors.Is(err, ErrPartitionOverlap) {
+		t.Error(err)
+	}
+	if table.Partitions[0].SizeInSectors() != 20480 {
+		t.Error("Changed on error")
+	}
+}
@@ -0,0 +1,91 @@
+package gpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const backupBlockSize = 512 // Size of MBR and header blocks in sgdisk backup, independent of sector size
+
+// ExportBackup - write the table in format of "sgdisk --backup", which can be restored by "sgdisk --load-backup".
+// Blocks are written one after another:
+//
+//	bytes 0-511: protective MBR
+//	bytes 512-1023: primary header
+//	bytes 1024-1535: backup header
+//	bytes 1536-...: partitions array, PartitionsArrLen entries of PartitionEntrySize bytes
+//
+// Headers are cut or padded by zeroes to 512 bytes, independent of SectorSize.
+func (this Table) ExportBackup(writer io.Writer) error {
+	primary := this
+	if this.IsBackupHeader() {
+		primary = this.CreateOtherSideTable()
+	}
+	backup := primary.CreateOtherSideTable()
+
+	if err := NewProtectiveMBR(this.diskSectors()).write(writer); err != nil {
+		return err
+	}
+	for _, table := range []Table{primary, backup} {
+		header, err := table.serializedHeader()
+		if err != nil {
+			return err
+		}
+		block := make([]byte, backupBlockSize)
+		copy(block, header)
+		if _, err = writer.Write(block); err != nil {
+			return err
+		}
+	}
+	array, err := primary.PartitionArrayBytes()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(array)
+	return err
+}
+
+// ImportBackup - read primary table from backup, created by "sgdisk --backup" or ExportBackup.
+// MBR signature, CRC of the headers and of partitions array are checked.
+func ImportBackup(reader io.Reader, sectorSize uint64) (Table, error) {
+	if sectorSize < backupBlockSize {
+		return Table{}, fmt.Errorf("Sector size (%v) less then %v", sectorSize, backupBlockSize)
+	}
+
+	var mbr ProtectiveMBR
+	if err := binary.Read(reader, binary.LittleEndian, &mbr); err != nil {
+		return Table{}, err
+	}
+	if mbr.Signature != mbrSignature {
+		return Table{}, fmt.Errorf("%w: %#04x", ErrMBRSignature, mbr.Signature)
+	}
+
+	var headers [2]Header
+	for i := range headers {
+		header, err := readHeader(reader, backupBlockSize)
+		if err != nil {
+			return Table{}, err
+		}
+		header.TrailingBytes = append(header.TrailingBytes, make([]byte, sectorSize-backupBlockSize)...)
+		headers[i] = header
+	}
+
+	table := Table{SectorSize: sectorSize, Header: headers[0]}
+	// PartitionsArrLen is read from the blob and can be broken, so partitions grow by append only
+	for i := uint32(0); i < table.Header.PartitionsArrLen; i++ {
+		p, err := readPartition(reader, table.Header.PartitionEntrySize)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Table{}, fmt.Errorf("%w: read %v of %v entries", ErrTruncatedArray, i, table.Header.PartitionsArrLen)
+		}
+		if err != nil {
+			return Table{}, err
+		}
+		table.Partitions = append(table.Partitions, p)
+	}
+	if table.calcPartitionsCRC() != table.Header.PartitionsCRC {
+		return Table{}, ErrBadPartitionsCRC
+	}
+	return table, nil
+}
@@ -0,0 +1,98 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExportBackup(t *testing.T) {
+	table := readTestTable(t)
+	buf := &bytes.Buffer{}
+	if err := table.ExportBackup(buf); err != nil {
+		t.Fatal(err)
+	}
+	blob := buf.Bytes()
+	if len(blob) != 3*512+128*128 {
+		t.Fatal(len(blob))
+	}
+
+	if err := table.RequireProtectiveMBR(bytes.NewReader(blob)); err != nil {
+		t.Error(err)
+	}
+
+	header, err := table.HeaderSectorBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob[512:1024], header) {
+		t.Error("Bad primary header")
+	}
+	backup, err := table.CreateOtherSideTable().HeaderSectorBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob[1024:1536], backup) {
+		t.Error("Bad backup header")
+	}
+	array, err := table.PartitionArrayBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob[1536:], array) {
+		t.Error("Bad partitions array")
+	}
+
+	// Same blob from backup table
+	buf.Reset()
+	if err = table.CreateOtherSideTable().ExportBackup(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), blob) {
+		t.Error("Blob from backup table differs")
+	}
+}
+
+func TestImportBackup(t *testing.T) {
+	table := readTestTable(t)
+	buf := &bytes.Buffer{}
+	if err := table.ExportBackup(buf); err != nil {
+		t.Fatal(err)
+	}
+	blob := buf.Bytes()
+
+	imported, err := ImportBackup(bytes.NewReader(blob), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.SectorSize != table.SectorSize || !HeadersEqual(imported.Header, table.Header) ||
+		!reflect.DeepEqual(imported.Partitions, table.Partitions) {
+		t.Error("Imported table differs")
+	}
+
+	broken := append([]byte{}, blob...)
+	broken[len(broken)-1]++
+	if _, err = ImportBackup(bytes.NewReader(broken), 512); !errors.Is(err, ErrBadPartitionsCRC) {
+		t.Error(err)
+	}
+	broken = append([]byte{}, blob...)
+	broken[512+24]++
+	if _, err = ImportBackup(bytes.NewReader(broken), 512); !errors.Is(err, ErrBadHeaderCRC) {
+		t.Error(err)
+	}
+	if _, err = ImportBackup(bytes.NewReader(blob[:len(blob)-1]), 512); !errors.Is(err, ErrTruncatedArray) {
+		t.Error(err)
+	}
+
+	// Huge PartitionsArrLen with valid header CRC
+	header := table.Header
+	header.PartitionsArrLen = 0xFFFFFFFF
+	headerBuf := &bytes.Buffer{}
+	header.write(headerBuf, true)
+	broken = append([]byte{}, blob...)
+	copy(broken[backupBlockSize:], headerBuf.Bytes()[:backupBlockSize])
+	if _, err = ImportBackup(bytes.NewReader(broken), 512); !errors.Is(err, ErrTruncatedArray) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,199 @@
+package gpt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrBadLayoutSpec = errors.New("Bad partition layout spec")
+)
+
+// Builder - fluent API for create new table. Errors are accumulated and returned by Build.
+//
+//	table, err := NewBuilder(diskSize, 512).
+//		AddPartition(1024*1024, "BIOS boot", "boot").
+//		AddPartition(10*1024*1024*1024, "Linux filesystem", "root").
+//		Build()
+type Builder struct {
+	table Table
+	errs  BuildError
+}
+
+// BuildError - all errors, accumulated by Builder
+type BuildError []error
+
+func (this BuildError) Error() string {
+	messages := make([]string, len(this))
+	for i, err := range this {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// NewBuilder - start build of table for the disk. Error for disk, which is too small for GPT metadata,
+// is returned by Build.
+func NewBuilder(diskSizeBytes uint64, sectorSize uint64) *Builder {
+	table, err := newTableForLayout(diskSizeBytes, sectorSize)
+	res := &Builder{table: table}
+	if err != nil {
+		res.errs = append(res.errs, err)
+	}
+	return res
+}
+
+// AddPartition - add partition of type with typeName from the registry after previous partitions,
+// aligned by RecommendedAlignment.
+func (this *Builder) AddPartition(sizeBytes uint64, typeName, name string) *Builder {
+	var p Partition
+	if err := p.SetTypeName(typeName); err != nil {
+		this.errs = append(this.errs, err)
+		return this
+	}
+	if _, err := this.table.AddPartition(p.Type, sizeBytes, 0, name); err != nil {
+		this.errs = append(this.errs, err)
+	}
+	return this
+}
+
+// DiskGUID - set disk guid from string like "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+func (this *Builder) DiskGUID(s string) *Builder {
+	guid, err := StringToGuid(s)
+	if err != nil {
+		this.errs = append(this.errs, err)
+		return this
+	}
+	this.table.Header.DiskGUID = guid
+	return this
+}
+
+// Build - return the table with calculated CRCs or BuildError.
+func (this *Builder) Build() (Table, error) {
+	if len(this.errs) > 0 {
+		return Table{}, this.errs
+	}
+	res := this.table.copy()
+	res.Touch()
+	return res, nil
+}
+
+// PartitionSpec - description of partition for BuildLayout.
+type PartitionSpec struct {
+	SizeBytes        uint64 // Rounded up to sectors, ignored for Rest partition
+	Rest             bool   // Partition fill all space, which is not used by other partitions. Only one spec can be Rest.
+	Type             PartType
+	Name             string
+	AlignmentSectors uint64 // Alignment of partition start, zero mean RecommendedAlignment for the type
+}
+
+// BuildLayout - create new table with partitions from specs, placed one after another in order of specs.
+// Partitions after Rest partition are placed to end of the disk, Rest partition fill space between them.
+func BuildLayout(diskSizeBytes uint64, sectorSize uint64, specs []PartitionSpec) (Table, error) {
+	table, err := newTableForLayout(diskSizeBytes, sectorSize)
+	if err != nil {
+		return Table{}, err
+	}
+	if len(specs) > len(table.Partitions) {
+		return Table{}, fmt.Errorf("%w: %v partitions", ErrNoFreeSlot, len(specs))
+	}
+
+	restIndex := -1
+	for i, spec := range specs {
+		switch {
+		case spec.Rest && restIndex != -1:
+			return Table{}, fmt.Errorf("%w: rest partitions %v and %v", ErrBadLayoutSpec, restIndex, i)
+		case spec.Rest:
+			restIndex = i
+		case spec.SizeBytes == 0:
+			return Table{}, fmt.Errorf("%w: zero size of partition %v", ErrBadLayoutSpec, i)
+		}
+	}
+
+	alignment := func(spec PartitionSpec) uint64 {
+		if spec.AlignmentSectors == 0 {
+			return RecommendedAlignment(spec.Type, table.SectorSize)
+		}
+		return spec.AlignmentSectors
+	}
+	sizeSectors := func(spec PartitionSpec) uint64 {
+		return (spec.SizeBytes + table.SectorSize - 1) / table.SectorSize
+	}
+
+	// Head partitions from start of usable space
+	next := table.Header.FirstUsableLBA
+	headEnd := len(specs)
+	if restIndex != -1 {
+		headEnd = restIndex
+	}
+	for i := 0; i < headEnd; i++ {
+		start := alignUp(next, alignment(specs[i]))
+		size := sizeSectors(specs[i])
+		if start < next || start > table.Header.LastUsableLBA || size > table.Header.LastUsableLBA-start+1 {
+			return Table{}, fmt.Errorf("%w: partition %v", ErrNoSpace, i)
+		}
+		table.Partitions[i] = Partition{FirstLBA: start, LastLBA: start + size - 1}
+		next = start + size
+	}
+	if restIndex == -1 {
+		return table.fillLayout(specs)
+	}
+
+	// Tail partitions from end of usable space
+	end := table.Header.LastUsableLBA + 1
+	for i := len(specs) - 1; i > restIndex; i-- {
+		size := sizeSectors(specs[i])
+		if size > end-next {
+			return Table{}, fmt.Errorf("%w: partition %v", ErrNoSpace, i)
+		}
+		start := end - size
+		start -= start % alignment(specs[i])
+		if start < next {
+			return Table{}, fmt.Errorf("%w: partition %v", ErrNoSpace, i)
+		}
+		table.Partitions[i] = Partition{FirstLBA: start, LastLBA: start + size - 1}
+		end = start
+	}
+
+	start := alignUp(next, alignment(specs[restIndex]))
+	if start < next || start >= end {
+		return Table{}, fmt.Errorf("%w: rest partition %v", ErrNoSpace, restIndex)
+	}
+	table.Partitions[restIndex] = Partition{FirstLBA: start, LastLBA: end - 1}
+	return table.fillLayout(specs)
+}
+
+// New empty table and ErrNoSpace if the disk can't hold both headers, both partitions arrays and one usable sector.
+// The table is returned on error too.
+func newTableForLayout(diskSizeBytes uint64, sectorSize uint64) (Table, error) {
+	table := NewTable(diskSizeBytes, &NewTableArgs{SectorSize: sectorSize})
+	arraySectors := table.Header.partitionsTableSectors(table.SectorSize)
+	if diskSectors := diskSizeBytes / table.SectorSize; diskSectors < 2*(arraySectors+2) {
+		return table, fmt.Errorf("%w: disk of %v sectors is too small for GPT metadata", ErrNoSpace, diskSectors)
+	}
+	return table, nil
+}
+
+// NewLinuxLayout - create table with EFI System partition of espSizeBytes and Linux filesystem partition, which fill
+// rest of the disk. Both partitions are aligned to 1MiB. Protective MBR have to be written for the table separately.
+func NewLinuxLayout(diskSizeBytes uint64, sectorSize uint64, espSizeBytes uint64) (Table, error) {
+	return BuildLayout(diskSizeBytes, sectorSize, []PartitionSpec{
+		{SizeBytes: espSizeBytes, Type: GUID_EFI_SYSTEM, Name: "EFI System"},
+		{Rest: true, Type: GUID_LINUX_FS, Name: "Linux filesystem"},
+	})
+}
+
+// Set types, names and guids of placed partitions and calculate CRCs
+func (this Table) fillLayout(specs []PartitionSpec) (Table, error) {
+	for i, spec := range specs {
+		p := &this.Partitions[i]
+		p.Type = spec.Type
+		p.Id = NewGUID()
+		p.TrailingBytes = this.entryTrailingBytes(nil)
+		if err := p.SetName(spec.Name); err != nil {
+			return Table{}, fmt.Errorf("partition %v: %w", i, err)
+		}
+	}
+	this.Touch()
+	return this, nil
+}
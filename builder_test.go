@@ -0,0 +1,135 @@
+package gpt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	table, err := NewBuilder(1024*1024*100, 512).
+		DiskGUID("C12A7328-F81F-11D2-BA4B-00A0C93EC93B").
+		AddPartition(1024*1024, "BIOS boot", "boot").
+		AddPartition(1024*1024*10, "Linux filesystem", "root").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.DiskGUID.String() != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Error(table.Header.DiskGUID)
+	}
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	boot, root := table.Partitions[0], table.Partitions[1]
+	if boot.Type != GUID_BIOS_BOOT || boot.Name() != "boot" || boot.FirstLBA != 2048 || boot.LastLBA != 4095 {
+		t.Error("Boot: ", boot.Type, boot.Name(), boot.FirstLBA, boot.LastLBA)
+	}
+	if root.Type != GUID_LINUX_FS || root.Name() != "root" || root.FirstLBA != 4096 || root.SizeInSectors() != 20480 {
+		t.Error("Root: ", root.Type, root.Name(), root.FirstLBA, root.SizeInSectors())
+	}
+	if boot.Id == root.Id {
+		t.Error("Same partition ids")
+	}
+	if !table.Partitions[2].IsEmpty() {
+		t.Error("Extra partition")
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewBuilder(1024*1024*100, 512).
+		DiskGUID("bad guid").
+		AddPartition(1024*1024, "Unknown type", "boot").
+		AddPartition(1024*1024*1024, "Linux filesystem", "too big").
+		AddPartition(1024*1024, "Linux filesystem", "ok").
+		Build()
+	var buildErr BuildError
+	if !errors.As(err, &buildErr) || len(buildErr) != 3 {
+		t.Fatal(err)
+	}
+	if !errors.Is(buildErr[1], ErrUnknownPartType) || !errors.Is(buildErr[2], ErrNoSpace) {
+		t.Error(err)
+	}
+	_, err = NewBuilder(10*512, 512).Build()
+	if !errors.As(err, &buildErr) || len(buildErr) != 1 || !errors.Is(buildErr[0], ErrNoSpace) {
+		t.Error("Tiny disk: ", err)
+	}
+}
+
+func TestBuildLayout(t *testing.T) {
+	const diskSize = 1024 * 1024 * 100
+	table, err := BuildLayout(diskSize, 512, []PartitionSpec{
+		{SizeBytes: 1024 * 1024 * 10, Type: GUID_EFI_SYSTEM, Name: "esp"},
+		{Rest: true, Type: GUID_LINUX_FS, Name: "root"},
+		{SizeBytes: 1024 * 1024 * 20, Type: GUID_LVM, Name: "swap"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	esp, root, swap := table.Partitions[0], table.Partitions[1], table.Partitions[2]
+	if esp.Type != GUID_EFI_SYSTEM || esp.Name() != "esp" || esp.FirstLBA != 2048 || esp.SizeInSectors() != 20480 {
+		t.Error("ESP: ", esp.Type, esp.Name(), esp.FirstLBA, esp.SizeInSectors())
+	}
+	if swap.Type != GUID_LVM || swap.Name() != "swap" || swap.FirstLBA%2048 != 0 || swap.SizeInSectors() != 40960 ||
+		swap.LastLBA > table.Header.LastUsableLBA || table.Header.LastUsableLBA-swap.LastLBA >= 2048 {
+		t.Error("Swap: ", swap.Type, swap.Name(), swap.FirstLBA, swap.LastLBA)
+	}
+	if root.Type != GUID_LINUX_FS || root.Name() != "root" || root.FirstLBA != 22528 || root.LastLBA != swap.FirstLBA-1 {
+		t.Error("Root: ", root.Type, root.Name(), root.FirstLBA, root.LastLBA)
+	}
+	if esp.Id == root.Id || !table.Partitions[3].IsEmpty() {
+		t.Error("Bad guids or extra partitions")
+	}
+
+	_, err = BuildLayout(diskSize, 512, []PartitionSpec{{Rest: true}, {Rest: true}})
+	if !errors.Is(err, ErrBadLayoutSpec) {
+		t.Error(err)
+	}
+	_, err = BuildLayout(diskSize, 512, []PartitionSpec{{SizeBytes: diskSize}, {Rest: true}})
+	if !errors.Is(err, ErrNoSpace) {
+		t.Error(err)
+	}
+
+	// Disk too small for GPT metadata
+	for _, sectors := range []uint64{0, 10, 67} {
+		_, err = BuildLayout(sectors*512, 512, []PartitionSpec{{SizeBytes: 1024 * 1024}})
+		if !errors.Is(err, ErrNoSpace) {
+			t.Error(sectors, err)
+		}
+	}
+	table, err = BuildLayout(68*512, 512, []PartitionSpec{{SizeBytes: 512, AlignmentSectors: 1}})
+	if err != nil || table.Header.FirstUsableLBA != 34 || table.Header.LastUsableLBA != 34 {
+		t.Error("Single usable sector: ", err)
+	}
+}
+
+func TestNewLinuxLayout(t *testing.T) {
+	table, err := NewLinuxLayout(1024*1024*1024, 4096, 1024*1024*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	esp, root := table.Partitions[0], table.Partitions[1]
+	if esp.Type != GUID_EFI_SYSTEM || esp.FirstLBA != 256 || esp.SizeInSectors() != 25600 {
+		t.Error("ESP: ", esp.Type, esp.FirstLBA, esp.SizeInSectors())
+	}
+	if root.Type != GUID_LINUX_FS || root.FirstLBA != 25856 || root.LastLBA != table.Header.LastUsableLBA {
+		t.Error("Root: ", root.Type, root.FirstLBA, root.LastLBA)
+	}
+	if esp.Name() != "EFI System" || root.Name() != "Linux filesystem" {
+		t.Error(esp.Name(), root.Name())
+	}
+	if table.DataPartitions()[0] != 1 || !table.Partitions[2].IsEmpty() {
+		t.Error("Bad partitions")
+	}
+	if _, err = NewLinuxLayout(10*4096, 4096, 4096); !errors.Is(err, ErrNoSpace) {
+		t.Error("Tiny disk: ", err)
+	}
+}
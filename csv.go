@@ -0,0 +1,40 @@
+package gpt
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var csvHeader = []string{"Index", "Type name", "Type GUID", "Partition GUID", "First LBA", "Last LBA", "Size bytes", "Name", "Flags", "Size"}
+
+// WriteCSV - write header row and row for every non-empty partition, columns are:
+// index, type name, type guid, partition guid, first LBA, last LBA, size in bytes, name, flags string, human readable size.
+func (this Table) WriteCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for i, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		row := []string{
+			strconv.Itoa(i),
+			p.TypeName(),
+			p.Type.String(),
+			p.Id.String(),
+			strconv.FormatUint(p.FirstLBA, 10),
+			strconv.FormatUint(p.LastLBA, 10),
+			strconv.FormatUint(p.SizeInSectors()*this.SectorSize, 10),
+			p.Name(),
+			p.FlagsString(),
+			FormatBytes(p.SizeInSectors() * this.SectorSize),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
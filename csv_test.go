@@ -0,0 +1,32 @@
+package gpt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	table := readTestTable(t)
+	buf := &bytes.Buffer{}
+	if err := table.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Fatal(rows)
+	}
+	esp := []string{"0", "EFI System", "C12A7328-F81F-11D2-BA4B-00A0C93EC93B", "DC2F50B0-98DE-4681-A868-42E9FEBD6E3E",
+		"2048", "780287", "398458880", table.Partitions[0].Name(), "[]", "380.0 MiB"}
+	if !reflect.DeepEqual(rows[1], esp) {
+		t.Error(rows[1])
+	}
+	if rows[3][0] != "2" || rows[3][7] != "primary" {
+		t.Error(rows[3])
+	}
+}
@@ -0,0 +1,125 @@
+package gpt
+
+import "fmt"
+
+// FixAction - suggested way to repair a problem found by Table.Diagnose
+type FixAction int
+
+const (
+	FixRecalcCRC  FixAction = iota // Recalculate header and/or partitions CRC
+	FixGeometry                    // Move backup header and LastUsableLBA for the real disk size
+	FixFromBackup                  // Restore the table from the other copy
+	FixManual                      // Can't be fixed automatically
+)
+
+func (this FixAction) String() string {
+	switch this {
+	case FixRecalcCRC:
+		return "FixRecalcCRC"
+	case FixGeometry:
+		return "FixGeometry"
+	case FixFromBackup:
+		return "FixFromBackup"
+	case FixManual:
+		return "FixManual"
+	default:
+		return fmt.Sprintf("FixAction(%d)", int(this))
+	}
+}
+
+// ProblemKind - kind of problem found by Table.Diagnose
+type ProblemKind int
+
+const (
+	ProblemBadSignature ProblemKind = iota
+	ProblemHeaderCRC
+	ProblemPartitionsCRC
+	ProblemGeometry
+	ProblemOverlap
+	ProblemNonZeroReserved
+)
+
+type Problem struct {
+	Kind        ProblemKind
+	Description string
+	Fix         FixAction
+}
+
+type Diagnosis struct {
+	Problems []Problem
+}
+
+// Ok - true if no problems found
+func (this Diagnosis) Ok() bool {
+	return len(this.Problems) == 0
+}
+
+// Diagnose - check the table and return list of problems with suggested fixes.
+// diskSizeBytes - real size of the disk, used for geometry check. 0 - skip geometry check.
+func (this Table) Diagnose(diskSizeBytes uint64) (res Diagnosis) {
+	add := func(kind ProblemKind, fix FixAction, format string, args ...interface{}) {
+		res.Problems = append(res.Problems, Problem{Kind: kind, Description: fmt.Sprintf(format, args...), Fix: fix})
+	}
+
+	if string(this.Header.Signature[:]) != gptSignature {
+		add(ProblemBadSignature, FixFromBackup, "Bad GPT signature: %q", this.Header.Signature[:])
+	}
+	if crc := this.Header.calcCRC(); crc != this.Header.CRC {
+		add(ProblemHeaderCRC, FixRecalcCRC, "Header CRC %#08x, expected %#08x", this.Header.CRC, crc)
+	}
+	if crc := this.calcPartitionsCRC(); crc != this.Header.PartitionsCRC {
+		add(ProblemPartitionsCRC, FixRecalcCRC, "Partitions CRC %#08x, expected %#08x", this.Header.PartitionsCRC, crc)
+	}
+	if diskSizeBytes != 0 && this.SectorSize != 0 && this.BackupIsMisplaced(diskSizeBytes) {
+		add(ProblemGeometry, FixGeometry, "Backup header at LBA %v, but last disk LBA is %v", this.diskSectors()-1, diskSizeBytes/this.SectorSize-1)
+	}
+	for _, pair := range this.overlappedPartitions() {
+		add(ProblemOverlap, FixManual, "Partitions %v and %v overlap", pair[0], pair[1])
+	}
+	nonZeroReserved := 0
+	for _, p := range this.Partitions {
+		if p.HasNonZeroReserved() {
+			nonZeroReserved++
+		}
+	}
+	if nonZeroReserved > 0 {
+		add(ProblemNonZeroReserved, FixManual, "%v partition entries have non-zero reserved bytes", nonZeroReserved)
+	}
+	return res
+}
+
+// BackupIsMisplaced - true if backup header isn't in the last sector of the disk. Usually after disk resize.
+func (this Table) BackupIsMisplaced(diskSizeBytes uint64) bool {
+	return this.diskSectors() != diskSizeBytes/this.SectorSize
+}
+
+// RepairBackupLocation - move backup header to the last sector of the disk and backup partitions array right before it,
+// LastUsableLBA is moved with them. Usually it is needed after disk grown. No-op with fixed=false if backup header
+// is already at its place. The table isn't changed on error.
+func (this *Table) RepairBackupLocation(diskSizeBytes uint64) (fixed bool, err error) {
+	if !this.BackupIsMisplaced(diskSizeBytes) {
+		return false, nil
+	}
+
+	primary := *this
+	if this.IsBackupHeader() {
+		primary = this.CreateOtherSideTable()
+	}
+	diskSectors := diskSizeBytes / this.SectorSize
+	if diskSectors < primary.Header.partitionsTableSectors(this.SectorSize)+2+primary.Header.FirstUsableLBA {
+		return false, fmt.Errorf("%w: disk of %v sectors is too small for the table", ErrWriteOutOfDisk, diskSectors)
+	}
+
+	res := primary.CreateTableForNewDiskSize(diskSectors)
+	for i, p := range res.Partitions {
+		if !p.IsEmpty() && p.LastLBA > res.Header.LastUsableLBA {
+			return false, fmt.Errorf("%w: partition %v ends at %v, new last usable LBA %v", ErrPartitionBeforeUsable, i, p.LastLBA, res.Header.LastUsableLBA)
+		}
+	}
+	if this.IsBackupHeader() {
+		res = res.CreateOtherSideTable()
+	}
+	res.Touch()
+	*this = res
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package gpt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiagnoseOk(t *testing.T) {
+	table := readTestTable(t)
+	d := table.Diagnose(testDiskSize)
+	if !d.Ok() {
+		t.Error(d.Problems)
+	}
+}
+
+func TestDiagnoseStaleCRC(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].LastLBA--
+
+	d := table.Diagnose(testDiskSize)
+	if len(d.Problems) != 1 {
+		t.Fatal(d.Problems)
+	}
+	if d.Problems[0].Kind != ProblemPartitionsCRC {
+		t.Error("Kind: ", d.Problems[0].Kind)
+	}
+	if d.Problems[0].Fix != FixRecalcCRC {
+		t.Error("Fix: ", d.Problems[0].Fix)
+	}
+}
+
+func TestDiagnoseGeometryAndOverlap(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].LastLBA = table.Partitions[2].FirstLBA
+	table.Header.PartitionsCRC = table.calcPartitionsCRC()
+	table.Header.CRC = table.Header.calcCRC()
+
+	d := table.Diagnose(testDiskSize * 2)
+	if len(d.Problems) != 2 {
+		t.Fatal(d.Problems)
+	}
+	if d.Problems[0].Kind != ProblemGeometry || d.Problems[0].Fix != FixGeometry {
+		t.Error("Geometry: ", d.Problems[0])
+	}
+	if d.Problems[1].Kind != ProblemOverlap || d.Problems[1].Fix != FixManual {
+		t.Error("Overlap: ", d.Problems[1])
+	}
+}
+
+func TestDiagnoseNonZeroReserved(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[0].PartNameUTF16[70] = 'x'
+	table.Partitions[2].PartNameUTF16[70] = 'x'
+	table.Touch()
+
+	d := table.Diagnose(testDiskSize)
+	if len(d.Problems) != 1 || d.Problems[0].Kind != ProblemNonZeroReserved || d.Problems[0].Fix != FixManual {
+		t.Fatal(d.Problems)
+	}
+	if d.Problems[0].Description != "2 partition entries have non-zero reserved bytes" {
+		t.Error(d.Problems[0].Description)
+	}
+}
+
+func TestBackupIsMisplaced(t *testing.T) {
+	table := readTestTable(t)
+	if table.BackupIsMisplaced(testDiskSize) {
+		t.Error("Primary")
+	}
+	if table.CreateOtherSideTable().BackupIsMisplaced(testDiskSize) {
+		t.Error("Backup")
+	}
+
+	// Disk resized, backup stranded in the middle of the disk
+	if !table.BackupIsMisplaced(testDiskSize + 1024*1024*1024) {
+		t.Error("Stranded backup")
+	}
+}
+
+func TestRepairBackupLocation(t *testing.T) {
+	table := readTestTable(t)
+	if fixed, err := table.RepairBackupLocation(testDiskSize); fixed || err != nil {
+		t.Error("Correct table: ", fixed, err)
+	}
+
+	// Disk grown by 1GiB, backup stranded in the middle of the disk
+	const newSize = testDiskSize + 1024*1024*1024
+	const lastLBA = newSize/512 - 1
+	for _, table := range []Table{readTestTable(t), readTestTable(t).CreateOtherSideTable()} {
+		isBackup := table.IsBackupHeader()
+		fixed, err := table.RepairBackupLocation(newSize)
+		if !fixed || err != nil {
+			t.Fatal(isBackup, fixed, err)
+		}
+		if table.BackupHeaderLBA() != lastLBA || table.Header.LastUsableLBA != lastLBA-33 || table.IsBackupHeader() != isBackup {
+			t.Error(isBackup, table.Header)
+		}
+		backup := table
+		if !isBackup {
+			backup = table.CreateOtherSideTable()
+		}
+		if backup.Header.PartitionsTableStartLBA != lastLBA-32 {
+			t.Error(isBackup, "Backup partitions array: ", backup.Header.PartitionsTableStartLBA)
+		}
+		if err = table.Validate(); err != nil {
+			t.Error(isBackup, err)
+		}
+	}
+
+	// Disk shrunk under the last partition
+	table = readTestTable(t)
+	if fixed, err := table.RepairBackupLocation(testDiskSize - 1024*1024); fixed || !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(fixed, err)
+	}
+	if table.BackupIsMisplaced(testDiskSize) {
+		t.Error("Changed on error")
+	}
+}
@@ -0,0 +1,331 @@
+package gpt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrBadPartitionIndex = errors.New("Bad partition index")
+	ErrEmptyPartition    = errors.New("Partition is empty")
+	ErrOutOfUsableSpace  = errors.New("Partition is out of usable space")
+	ErrPartitionOverlap  = errors.New("Partitions overlap")
+	ErrNoFreeSlot        = errors.New("No free slot in partitions array")
+	ErrNoSpace           = errors.New("No free space for partition")
+	ErrBadEntrySize      = errors.New("Bad partition entry size")
+)
+
+// AddPartition - create partition of sizeBytes (rounded up to sectors) in first empty slot of the table.
+// The partition is placed to first free region, which can hold it, start of the partition is aligned to alignmentSectors.
+// Zero alignment mean RecommendedAlignment for the type. Return index of created partition.
+func (this *Table) AddPartition(typ PartType, sizeBytes uint64, alignmentSectors uint64, name string) (int, error) {
+	return this.AddPartitionWithID(typ, sizeBytes, alignmentSectors, name, NewGUID())
+}
+
+// AddPartitionWithID - same as AddPartition, but partition guid is id instead of random.
+func (this *Table) AddPartitionWithID(typ PartType, sizeBytes uint64, alignmentSectors uint64, name string, id Guid) (int, error) {
+	index := -1
+	for i := 0; i < len(this.Partitions) && uint32(i) < this.Header.PartitionsArrLen; i++ {
+		if this.Partitions[i].IsEmpty() {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return -1, ErrNoFreeSlot
+	}
+
+	sizeSectors := (sizeBytes + this.SectorSize - 1) / this.SectorSize
+	if sizeSectors == 0 {
+		return -1, fmt.Errorf("%w: zero size", ErrNoSpace)
+	}
+	if alignmentSectors == 0 {
+		alignmentSectors = RecommendedAlignment(typ, this.SectorSize)
+	}
+
+	p := Partition{Type: typ, Id: id, TrailingBytes: this.entryTrailingBytes(nil)}
+	if err := p.SetName(name); err != nil {
+		return -1, err
+	}
+	for _, region := range this.FreeRegions() {
+		start := alignUp(region.FirstLBA, alignmentSectors)
+		if start >= region.FirstLBA && start <= region.LastLBA && region.LastLBA-start+1 >= sizeSectors {
+			p.FirstLBA = start
+			p.LastLBA = start + sizeSectors - 1
+			this.Partitions[index] = p
+			this.Touch()
+			return index, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: %v sectors aligned to %v", ErrNoSpace, sizeSectors, alignmentSectors)
+}
+
+// MovePartition - change FirstLBA of the partition and keep its size. Change metadata only, data on disk isn't moved.
+func (this *Table) MovePartition(index int, newFirstLBA uint64) error {
+	if err := this.checkNonEmptyPartition(index); err != nil {
+		return err
+	}
+
+	p := this.Partitions[index]
+	newLastLBA := newFirstLBA + (p.LastLBA - p.FirstLBA)
+	if newLastLBA < newFirstLBA {
+		return fmt.Errorf("%w: last LBA overflow", ErrOutOfUsableSpace)
+	}
+	p.FirstLBA, p.LastLBA = newFirstLBA, newLastLBA
+	if err := this.checkPlace(p, index); err != nil {
+		return err
+	}
+
+	this.Partitions[index] = p
+	this.Touch()
+	return nil
+}
+
+// ExpandToFill - grow the partition up to next partition or LastUsableLBA. It is no-op if there is no free space
+// after the partition.
+func (this *Table) ExpandToFill(index int) error {
+	if err := this.checkNonEmptyPartition(index); err != nil {
+		return err
+	}
+	p := &this.Partitions[index]
+	lastLBA := this.Header.LastUsableLBA
+	for i, other := range this.Partitions {
+		if i != index && !other.IsEmpty() && other.FirstLBA > p.LastLBA && other.FirstLBA-1 < lastLBA {
+			lastLBA = other.FirstLBA - 1
+		}
+	}
+	if lastLBA <= p.LastLBA {
+		return nil
+	}
+	p.LastLBA = lastLBA
+	this.Touch()
+	return nil
+}
+
+// MergePartitionsFrom - copy non-empty partitions of other table to empty slots of the table, in order of other slots.
+// Partitions, which overlap partitions of the table, are out of usable space or have no free slot, are skipped:
+// their indexes in other are returned as conflicts. Return count of added partitions.
+func (this *Table) MergePartitionsFrom(other Table) (added int, conflicts []int) {
+	slot := 0
+	for i, p := range other.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		for slot < len(this.Partitions) && uint32(slot) < this.Header.PartitionsArrLen && !this.Partitions[slot].IsEmpty() {
+			slot++
+		}
+		if slot >= len(this.Partitions) || uint32(slot) >= this.Header.PartitionsArrLen || this.checkPlace(p, -1) != nil {
+			conflicts = append(conflicts, i)
+			continue
+		}
+		p.TrailingBytes = this.entryTrailingBytes(p.TrailingBytes)
+		this.Partitions[slot] = p
+		added++
+	}
+	this.Touch()
+	return added, conflicts
+}
+
+// SetPartitionAt - put the partition to slot index of partitions array. Old value of the slot is replaced.
+// TrailingBytes of p are truncated or padded by zeroes to PartitionEntrySize.
+func (this *Table) SetPartitionAt(index int, p Partition) error {
+	if err := this.checkIndex(index); err != nil {
+		return err
+	}
+	if uint32(index) >= this.Header.PartitionsArrLen {
+		return fmt.Errorf("%w: %v, PartitionsArrLen %v", ErrBadPartitionIndex, index, this.Header.PartitionsArrLen)
+	}
+	p.TrailingBytes = this.entryTrailingBytes(p.TrailingBytes)
+	if !p.IsEmpty() {
+		if err := this.checkPlace(p, index); err != nil {
+			return err
+		}
+	}
+	this.Partitions[index] = p
+	this.Touch()
+	return nil
+}
+
+// Compact - move non-empty partitions to the start of partitions array, keep their order. Rest of array is cleared.
+func (this *Table) Compact() {
+	next := 0
+	for _, p := range this.Partitions {
+		if !p.IsEmpty() {
+			this.Partitions[next] = p
+			next++
+		}
+	}
+	for i := next; i < len(this.Partitions); i++ {
+		this.Partitions[i] = Partition{TrailingBytes: this.entryTrailingBytes(nil)}
+	}
+	this.Touch()
+}
+
+// RemovePartition - clear partition entry. Data on disk isn't changed, use WipePartitionData before remove
+// the entry if the data must be destroyed.
+func (this *Table) RemovePartition(index int) error {
+	if err := this.checkIndex(index); err != nil {
+		return err
+	}
+	this.Partitions[index] = Partition{TrailingBytes: this.entryTrailingBytes(nil)}
+	this.Touch()
+	return nil
+}
+
+// WipePartitionData - fill sectors of the partition by zeroes. Partition entry isn't changed,
+// use RemovePartition after wipe for remove it from the table.
+func (this Table) WipePartitionData(writer io.WriteSeeker, index int) error {
+	if err := this.checkNonEmptyPartition(index); err != nil {
+		return err
+	}
+	p := this.Partitions[index]
+	start, ok := mul(int64(p.FirstLBA), int64(this.SectorSize))
+	if !ok {
+		return fmt.Errorf("Seek overflow when wipe partition")
+	}
+	if _, err := writer.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	const chunkSize = 1024 * 1024
+	zeroes := make([]byte, chunkSize)
+	for left := p.SizeInSectors() * this.SectorSize; left > 0; {
+		n := uint64(chunkSize)
+		if left < n {
+			n = left
+		}
+		if _, err := writer.Write(zeroes[:n]); err != nil {
+			return err
+		}
+		left -= n
+	}
+	return nil
+}
+
+func (this Table) checkIndex(index int) error {
+	if index < 0 || index >= len(this.Partitions) {
+		return fmt.Errorf("%w: %v, partitions count %v", ErrBadPartitionIndex, index, len(this.Partitions))
+	}
+	return nil
+}
+
+func (this Table) checkNonEmptyPartition(index int) error {
+	if err := this.checkIndex(index); err != nil {
+		return err
+	}
+	if this.Partitions[index].IsEmpty() {
+		return fmt.Errorf("%w: %v", ErrEmptyPartition, index)
+	}
+	return nil
+}
+
+// Check if p can be placed to the table: it is within usable space and doesn't overlap other partitions.
+// Partition with index skipIndex (old place of p) doesn't checked for overlap.
+func (this Table) checkPlace(p Partition, skipIndex int) error {
+	if p.FirstLBA > p.LastLBA || p.FirstLBA < this.Header.FirstUsableLBA || p.LastLBA > this.Header.LastUsableLBA {
+		return fmt.Errorf("%w: [%v-%v], usable [%v-%v]", ErrOutOfUsableSpace, p.FirstLBA, p.LastLBA,
+			this.Header.FirstUsableLBA, this.Header.LastUsableLBA)
+	}
+	for i := range this.Partitions {
+		if i != skipIndex && !this.Partitions[i].IsEmpty() && this.Partitions[i].overlaps(p) {
+			return fmt.Errorf("%w: with partition %v", ErrPartitionOverlap, i)
+		}
+	}
+	return nil
+}
+
+// Copy of trailing bytes, truncated or padded by zeroes for partition entry size of the table.
+func (this Table) entryTrailingBytes(trailingBytes []byte) []byte {
+	res := make([]byte, int(this.Header.PartitionEntrySize)-standardPartitionEntrySize)
+	copy(res, trailingBytes)
+	return res
+}
+
+// SetEntrySize - change PartitionEntrySize, trailing bytes of entries are extended by zeroes or cut.
+// If partitions array grows, FirstUsableLBA and LastUsableLBA are moved for place the arrays.
+// The table isn't changed on error.
+func (this *Table) SetEntrySize(newSize uint32) error {
+	if newSize < standardPartitionEntrySize || newSize%8 != 0 {
+		return fmt.Errorf("%w: %v, it must be at least %v and multiple of 8", ErrBadEntrySize, newSize, standardPartitionEntrySize)
+	}
+
+	res := this.copy()
+	res.Header.PartitionEntrySize = newSize
+	for i := range res.Partitions {
+		res.Partitions[i].TrailingBytes = res.entryTrailingBytes(res.Partitions[i].TrailingBytes)
+	}
+
+	if minFirstUsable := res.minFirstUsableLBA(); minFirstUsable > res.Header.FirstUsableLBA {
+		if err := res.setFirstUsableLBA(minFirstUsable); err != nil {
+			return err
+		}
+	}
+	arraySectors := res.Header.partitionsTableSectors(res.SectorSize)
+	if res.BackupHeaderLBA() < arraySectors+1 {
+		return fmt.Errorf("%w: partitions array doesn't fit the disk", ErrBadEntrySize)
+	}
+	if maxLastUsable := res.BackupHeaderLBA() - arraySectors - 1; maxLastUsable < res.Header.LastUsableLBA {
+		for i, p := range res.Partitions {
+			if !p.IsEmpty() && p.LastLBA > maxLastUsable {
+				return fmt.Errorf("%w: partition %v ends at %v, new last usable LBA %v", ErrPartitionBeforeUsable, i, p.LastLBA, maxLastUsable)
+			}
+		}
+		res.Header.LastUsableLBA = maxLastUsable
+		if res.IsBackupHeader() {
+			res.Header.PartitionsTableStartLBA = maxLastUsable + 1
+		}
+	}
+
+	res.Touch()
+	*this = res
+	return nil
+}
+
+// Finalize - make table, built by hand, consistent for write: PartitionsArrLen is set to max(128, len(Partitions)),
+// partitions are padded by empty entries, trailing bytes of entries are fit to PartitionEntrySize (at least 128),
+// usable LBA range is shrunk if it overlaps the partitions arrays and CRCs are recalculated.
+func (this *Table) Finalize() {
+	if this.Header.PartitionEntrySize < standardPartitionEntrySize {
+		this.Header.PartitionEntrySize = standardPartitionEntrySize
+	}
+	this.Header.PartitionsArrLen = standardPartitionsArrLen
+	if len(this.Partitions) > standardPartitionsArrLen {
+		this.Header.PartitionsArrLen = uint32(len(this.Partitions))
+	}
+	for len(this.Partitions) < int(this.Header.PartitionsArrLen) {
+		this.Partitions = append(this.Partitions, Partition{})
+	}
+	for i := range this.Partitions {
+		this.Partitions[i].TrailingBytes = this.entryTrailingBytes(this.Partitions[i].TrailingBytes)
+	}
+
+	if minFirstUsable := this.minFirstUsableLBA(); this.Header.FirstUsableLBA < minFirstUsable {
+		this.Header.FirstUsableLBA = minFirstUsable
+	}
+	arraySectors := this.Header.partitionsTableSectors(this.SectorSize)
+	if backupHeader := this.BackupHeaderLBA(); backupHeader > arraySectors && this.Header.LastUsableLBA > backupHeader-arraySectors-1 {
+		this.Header.LastUsableLBA = backupHeader - arraySectors - 1
+	}
+	this.Touch()
+}
+
+// SetBootable - set LegacyBIOSBootable attribute of the partition and clear it for all other partitions.
+func (this *Table) SetBootable(index int) error {
+	if err := this.checkNonEmptyPartition(index); err != nil {
+		return err
+	}
+	for i := range this.Partitions {
+		p := &this.Partitions[i]
+		p.SetAttributes(p.Attributes().With(FlagLegacyBIOSBootable, i == index))
+	}
+	this.Touch()
+	return nil
+}
+
+// Touch - recalculate partitions and header CRC after direct change of the table.
+// All table changing methods call it themselves.
+func (this *Table) Touch() {
+	this.Header.PartitionsCRC = this.calcPartitionsCRC()
+	this.Header.CRC = this.Header.calcCRC()
+}
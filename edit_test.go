@@ -0,0 +1,358 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMovePartition(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[0].FirstLBA = 4096
+	table.Partitions[0].LastLBA = 6143
+
+	// to the gap at the start of the disk
+	if err := table.MovePartition(0, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[0].FirstLBA != 2048 || table.Partitions[0].LastLBA != 4095 {
+		t.Error(table.Partitions[0].FirstLBA, table.Partitions[0].LastLBA)
+	}
+	if !table.Diagnose(testDiskSize).Ok() {
+		t.Error("CRC")
+	}
+
+	// blocked by partition 1
+	if err := table.MovePartition(0, 780287); !errors.Is(err, ErrPartitionOverlap) {
+		t.Error(err)
+	}
+	if table.Partitions[0].FirstLBA != 2048 {
+		t.Error("Partition changed on error")
+	}
+
+	if err := table.MovePartition(0, 1); !errors.Is(err, ErrOutOfUsableSpace) {
+		t.Error(err)
+	}
+	if err := table.MovePartition(5, 2048); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+	if err := table.MovePartition(128, 2048); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestRemovePartition(t *testing.T) {
+	table := readTestTable(t)
+	if err := table.RemovePartition(1); err != nil {
+		t.Fatal(err)
+	}
+	if !table.Partitions[1].IsEmpty() || table.Partitions[1].FirstLBA != 0 {
+		t.Error("Partition isn't cleared")
+	}
+	if table.Partitions[2].IsEmpty() {
+		t.Error("Other partition removed")
+	}
+	if !table.Diagnose(testDiskSize).Ok() {
+		t.Error("CRC")
+	}
+	if err := table.RemovePartition(-1); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestWipePartitionData(t *testing.T) {
+	table := NewTable(1024*1024*4, nil)
+	table.Partitions[0] = Partition{Type: GUID_LVM, FirstLBA: 2048, LastLBA: 4095}
+	table.Partitions[1] = Partition{Type: GUID_LVM, FirstLBA: 4096, LastLBA: 6143}
+
+	disk := &randomWriteBuffer{buf: bytes.Repeat([]byte{0xFF}, 1024*1024*4)}
+	if err := table.WipePartitionData(disk, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(disk.buf[2048*512:4096*512], make([]byte, 2048*512)) {
+		t.Error("Partition isn't wiped")
+	}
+	if disk.buf[2048*512-1] != 0xFF || disk.buf[4096*512] != 0xFF {
+		t.Error("Wiped outside of the partition")
+	}
+	if len(disk.buf) != 1024*1024*4 {
+		t.Error("Disk size changed")
+	}
+
+	if err := table.WipePartitionData(disk, 2); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+	if err := table.WipePartitionData(disk, 200); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestSetPartitionAt(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	p := Partition{Type: GUID_LVM, Id: NewGUID(), FirstLBA: 2048, LastLBA: 4095, TrailingBytes: []byte{1, 2}}
+	if err := table.SetPartitionAt(5, p); err != nil {
+		t.Fatal(err)
+	}
+	for i, part := range table.Partitions {
+		if part.IsEmpty() != (i != 5) {
+			t.Error("Bad slot: ", i)
+		}
+	}
+	if table.Partitions[5].Id != p.Id || len(table.Partitions[5].TrailingBytes) != 0 {
+		t.Error("Bad partition")
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	p.FirstLBA, p.LastLBA = 4095, 5000
+	if err := table.SetPartitionAt(6, p); !errors.Is(err, ErrPartitionOverlap) {
+		t.Error(err)
+	}
+	if err := table.SetPartitionAt(5, p); err != nil {
+		t.Error("Overlap with replaced partition: ", err)
+	}
+	p.LastLBA = table.Header.LastUsableLBA + 1
+	if err := table.SetPartitionAt(6, p); !errors.Is(err, ErrOutOfUsableSpace) {
+		t.Error(err)
+	}
+	if err := table.SetPartitionAt(128, p); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestExpandToFill(t *testing.T) {
+	table := readTestTable(t)
+	crc := table.Header.CRC
+	if err := table.ExpandToFill(2); err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.CRC != crc || table.Partitions[2].LastLBA != table.Header.LastUsableLBA {
+		t.Error("Changed maximal partition")
+	}
+
+	table.Partitions[2].LastLBA -= 1000
+	table.Partitions[1].LastLBA -= 1000
+	table.Touch()
+	if err := table.ExpandToFill(2); err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[2].LastLBA != table.Header.LastUsableLBA {
+		t.Error("Last partition: ", table.Partitions[2].LastLBA)
+	}
+	if err := table.ExpandToFill(1); err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[1].LastLBA != table.Partitions[2].FirstLBA-1 {
+		t.Error("Middle partition: ", table.Partitions[1].LastLBA)
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := table.ExpandToFill(3); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+}
+
+func TestAddPartition(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	table.Partitions[0] = Partition{Type: GUID_LVM, FirstLBA: 2048, LastLBA: 4095}
+
+	index, err := table.AddPartition(GUID_LINUX_FS, 1000, 0, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := table.Partitions[index]
+	if index != 1 || p.FirstLBA != 4096 || p.LastLBA != 4097 || p.Name() != "data" || p.Type != GUID_LINUX_FS {
+		t.Error(index, p.FirstLBA, p.LastLBA, p.Name())
+	}
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// Before first partition, alignment 1 sector
+	index, err = table.AddPartition(GUID_LINUX_FS, 512*10, 1, "small")
+	if err != nil || table.Partitions[index].FirstLBA != 34 || table.Partitions[index].LastLBA != 43 {
+		t.Error(err, table.Partitions[index].FirstLBA, table.Partitions[index].LastLBA)
+	}
+
+	// MSR is aligned to 4KiB
+	index, err = table.AddPartition(GUID_MSR, 512*10, 0, "msr")
+	if err != nil || table.Partitions[index].FirstLBA != 48 {
+		t.Error(err, table.Partitions[index].FirstLBA)
+	}
+
+	if _, err = table.AddPartition(GUID_LINUX_FS, 1024*1024*10, 0, ""); !errors.Is(err, ErrNoSpace) {
+		t.Error(err)
+	}
+	if _, err = table.AddPartition(GUID_LINUX_FS, 0, 0, ""); !errors.Is(err, ErrNoSpace) {
+		t.Error(err)
+	}
+
+	for i := range table.Partitions {
+		table.Partitions[i].Type = GUID_LVM
+	}
+	if _, err = table.AddPartition(GUID_LINUX_FS, 512, 0, ""); !errors.Is(err, ErrNoFreeSlot) {
+		t.Error(err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	table.Partitions[1] = Partition{Type: GUID_LVM, FirstLBA: 5000, LastLBA: 5999}
+	table.Partitions[4] = Partition{Type: GUID_LINUX_FS, FirstLBA: 2048, LastLBA: 4095}
+	table.Partitions[5] = Partition{Type: GUID_BIOS_BOOT, FirstLBA: 34, LastLBA: 2047}
+	table.Partitions[6] = Partition{FirstLBA: 100} // empty with garbage
+
+	table.Compact()
+	if table.Partitions[0].Type != GUID_LVM || table.Partitions[1].Type != GUID_LINUX_FS || table.Partitions[2].Type != GUID_BIOS_BOOT {
+		t.Error("Bad order")
+	}
+	for i := 3; i < len(table.Partitions); i++ {
+		if !table.Partitions[i].IsEmpty() || table.Partitions[i].FirstLBA != 0 {
+			t.Error("Not empty: ", i)
+		}
+	}
+	if len(table.Partitions) != 128 {
+		t.Error(len(table.Partitions))
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetEntrySize(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	table := NewTable(diskSize, nil)
+	if _, err := table.AddPartition(GUID_LINUX_FS, 1024*1024, 0, "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := table.SetEntrySize(256); err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.PartitionEntrySize != 256 || len(table.Partitions[0].TrailingBytes) != 128 {
+		t.Error("Entry size: ", table.Header.PartitionEntrySize, len(table.Partitions[0].TrailingBytes))
+	}
+	if table.Header.FirstUsableLBA != 66 || table.Header.LastUsableLBA != diskSize/512-66 {
+		t.Error("Usable: ", table.Header.FirstUsableLBA, table.Header.LastUsableLBA)
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	disk := &randomWriteBuffer{buf: make([]byte, diskSize)}
+	if err := table.WriteToDisk(disk, diskSize); err != nil {
+		t.Fatal(err)
+	}
+	reader := bytes.NewReader(disk.buf)
+	reader.Seek(512, 0)
+	readed, err := ReadTable(reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readed.Header.PartitionEntrySize != 256 || readed.Partitions[0].Name() != "data" {
+		t.Error("Read: ", readed.Header.PartitionEntrySize, readed.Partitions[0].Name())
+	}
+	reader.Seek(diskSize-512, 0)
+	if _, err = ReadTable(reader, 512); err != nil {
+		t.Error("Backup: ", err)
+	}
+
+	if err = table.SetEntrySize(128); err != nil || len(table.Partitions[0].TrailingBytes) != 0 {
+		t.Error(err)
+	}
+	if err = table.SetEntrySize(132); !errors.Is(err, ErrBadEntrySize) {
+		t.Error(err)
+	}
+
+	// Last partition of the fixture ends at last usable LBA
+	fixture := readTestTable(t)
+	if err = fixture.SetEntrySize(256); !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(err)
+	}
+	if fixture.Header.PartitionEntrySize != 128 || len(fixture.Partitions[0].TrailingBytes) != 0 {
+		t.Error("Table changed on error")
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	table := NewTable(diskSize, nil)
+	table.Header.PartitionsArrLen = 3
+	table.Partitions = []Partition{
+		{Type: GUID_EFI_SYSTEM, FirstLBA: 2048, LastLBA: 4095},
+		{Type: GUID_LINUX_FS, FirstLBA: 4096, LastLBA: 8191},
+		{Type: GUID_LINUX_FS, FirstLBA: 8192, LastLBA: 10239},
+	}
+	table.Header.FirstUsableLBA = 2
+	table.Header.LastUsableLBA = diskSize/512 - 2
+
+	table.Finalize()
+	if table.Header.PartitionsArrLen != 128 || len(table.Partitions) != 128 {
+		t.Error("Partitions: ", table.Header.PartitionsArrLen, len(table.Partitions))
+	}
+	if table.Partitions[2].LastLBA != 10239 || !table.Partitions[3].IsEmpty() {
+		t.Error("Bad partitions")
+	}
+	if table.Header.FirstUsableLBA != 34 || table.Header.LastUsableLBA != diskSize/512-34 {
+		t.Error("Usable: ", table.Header.FirstUsableLBA, table.Header.LastUsableLBA)
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := table.WriteToDisk(&randomWriteBuffer{buf: make([]byte, diskSize)}, diskSize); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetBootable(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[0].SetAttributes(Attributes(0).With(FlagRequiredPartition, true))
+	if err := table.SetBootable(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.SetBootable(2); err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[1].Attributes().LegacyBIOSBootable() || !table.Partitions[2].Attributes().LegacyBIOSBootable() {
+		t.Error("Bootable isn't moved")
+	}
+	if table.Partitions[0].Attributes() != 1 {
+		t.Error("Other attributes changed: ", table.Partitions[0].Attributes())
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := table.SetBootable(3); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+	if !table.Partitions[2].Attributes().LegacyBIOSBootable() {
+		t.Error("Changed on error")
+	}
+}
+
+func TestMergePartitionsFrom(t *testing.T) {
+	table := readTestTable(t)
+	table.RemovePartition(1)
+
+	other := NewTable(testDiskSize, nil)
+	found := Partition{Type: GUID_LINUX_FS, Id: NewGUID(), FirstLBA: 780288, LastLBA: 80781311}
+	overlapped := Partition{Type: GUID_LINUX_FS, Id: NewGUID(), FirstLBA: 2048, LastLBA: 4095}
+	other.Partitions[3] = overlapped
+	other.Partitions[5] = found
+
+	added, conflicts := table.MergePartitionsFrom(other)
+	if added != 1 || !reflect.DeepEqual(conflicts, []int{3}) {
+		t.Error(added, conflicts)
+	}
+	if table.Partitions[1].Id != found.Id || table.Partitions[1].FirstLBA != 780288 {
+		t.Error(table.Partitions[1])
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+}
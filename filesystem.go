@@ -0,0 +1,100 @@
+package gpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Partition image without GPT given instead of disk image
+var ErrLooksLikeFilesystemImage = fmt.Errorf("%w, it looks like filesystem image instead of disk", ErrBadSignature)
+
+const filesystemSniffSize = 0x10000 + 0x48 // Bytes from start of partition, enough to detect known filesystems: btrfs magic
+
+// Places of filesystem magic numbers. ext superblock: https://www.kernel.org/doc/html/latest/filesystems/ext4/globals.html
+const (
+	extSuperblockOffset   = 1024
+	extMagicOffset        = extSuperblockOffset + 0x38
+	extCompatOffset       = extSuperblockOffset + 0x5C
+	extIncompatOffset     = extSuperblockOffset + 0x60
+	extCompatHasJournal   = 0x4
+	extIncompatExtents    = 0x40
+	extIncompatFlexBg     = 0x200
+	btrfsMagicOffset      = 0x10000 + 0x40
+	swapMagicOffset       = 4096 - 10 // End of first page, for 4KiB pages
+	unknownFilesystemName = "unknown"
+)
+
+// Name of filesystem, detected by magic numbers in first bytes of partition:
+// "FAT32", "FAT", "NTFS", "ext2", "ext3", "ext4", "XFS", "btrfs", "swap".
+// Empty string if filesystem isn't detected.
+func sniffFilesystemMagic(buf []byte) string {
+	hasAt := func(offset int, magic string) bool {
+		return len(buf) >= offset+len(magic) && string(buf[offset:offset+len(magic)]) == magic
+	}
+
+	switch {
+	case hasAt(3, "NTFS    "):
+		return "NTFS"
+	case hasAt(510, "\x55\xAA") && hasAt(0x52, "FAT32"):
+		return "FAT32"
+	case hasAt(510, "\x55\xAA") && hasAt(0x36, "FAT"):
+		return "FAT"
+	case hasAt(0, "XFSB"):
+		return "XFS"
+	case hasAt(extMagicOffset, "\x53\xEF") && len(buf) >= extIncompatOffset+4:
+		incompat := binary.LittleEndian.Uint32(buf[extIncompatOffset:])
+		switch {
+		case incompat&(extIncompatExtents|extIncompatFlexBg) != 0:
+			return "ext4"
+		case binary.LittleEndian.Uint32(buf[extCompatOffset:])&extCompatHasJournal != 0:
+			return "ext3"
+		default:
+			return "ext2"
+		}
+	case hasAt(swapMagicOffset, "SWAPSPACE2") || hasAt(swapMagicOffset, "SWAP-SPACE"):
+		return "swap"
+	case hasAt(btrfsMagicOffset, "_BHRfS_M"):
+		return "btrfs"
+	default:
+		return ""
+	}
+}
+
+// SniffFilesystem - detect filesystem of the partition by magic numbers in its first sectors. Result is one of
+// "FAT32", "FAT", "NTFS", "ext2", "ext3", "ext4", "XFS", "btrfs", "swap" or "unknown".
+// It doesn't depend on partition type.
+func SniffFilesystem(reader io.ReaderAt, p Partition, sectorSize uint64) (string, error) {
+	if p.IsEmpty() {
+		return "", ErrEmptyPartition
+	}
+	start, ok := mul(int64(p.FirstLBA), int64(sectorSize))
+	if !ok {
+		return "", fmt.Errorf("Read offset overflow for LBA %v", p.FirstLBA)
+	}
+	size := uint64(filesystemSniffSize)
+	if partSize := p.SizeInSectors() * sectorSize; partSize < size {
+		size = partSize
+	}
+
+	buf := make([]byte, size)
+	n, err := reader.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if fs := sniffFilesystemMagic(buf[:n]); fs != "" {
+		return fs, nil
+	}
+	return unknownFilesystemName, nil
+}
+
+// Detect filesystem at start of the reader, position of the reader is changed.
+func sniffImageStart(reader io.ReadSeeker) string {
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	buf := make([]byte, filesystemSniffSize)
+	n, _ := io.ReadFull(reader, buf)
+	return sniffFilesystemMagic(buf[:n])
+}
@@ -0,0 +1,104 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Boot sector of FAT32 filesystem
+func testFATImage() []byte {
+	buf := make([]byte, 1024*1024)
+	copy(buf, []byte{0xEB, 0x58, 0x90})
+	copy(buf[3:], "mkfs.fat")
+	copy(buf[0x52:], "FAT32   ")
+	buf[510], buf[511] = 0x55, 0xAA
+	return buf
+}
+
+func TestReadTableFilesystemImage(t *testing.T) {
+	reader := bytes.NewReader(testFATImage())
+	reader.Seek(512, 0)
+	_, err := ReadTable(reader, 512)
+	if !errors.Is(err, ErrLooksLikeFilesystemImage) || !errors.Is(err, ErrBadSignature) {
+		t.Error(err)
+	}
+	if err.Error() != "Bad GPT signature, it looks like filesystem image instead of disk: FAT32 filesystem at start of the image" {
+		t.Error(err)
+	}
+
+	reader = bytes.NewReader(make([]byte, 1024*1024))
+	reader.Seek(512, 0)
+	if _, err = ReadTable(reader, 512); err != ErrBadSignature {
+		t.Error(err)
+	}
+}
+
+func TestSniffFilesystemMagic(t *testing.T) {
+	buf := make([]byte, filesystemSniffSize)
+	if fs := sniffFilesystemMagic(buf); fs != "" {
+		t.Error(fs)
+	}
+	if fs := sniffFilesystemMagic(testFATImage()); fs != "FAT32" {
+		t.Error(fs)
+	}
+
+	copy(buf[3:], "NTFS    ")
+	if fs := sniffFilesystemMagic(buf); fs != "NTFS" {
+		t.Error(fs)
+	}
+
+	buf = make([]byte, filesystemSniffSize)
+	buf[1080], buf[1081] = 0x53, 0xEF
+	if fs := sniffFilesystemMagic(buf); fs != "ext2" {
+		t.Error(fs)
+	}
+	if fs := sniffFilesystemMagic(buf[:1024]); fs != "" {
+		t.Error(fs)
+	}
+
+	for fs, magic := range map[string]struct {
+		offset int
+		value  string
+	}{
+		"XFS":   {0, "XFSB"},
+		"swap":  {4086, "SWAPSPACE2"},
+		"btrfs": {0x10040, "_BHRfS_M"},
+	} {
+		buf = make([]byte, filesystemSniffSize)
+		copy(buf[magic.offset:], magic.value)
+		if res := sniffFilesystemMagic(buf); res != fs {
+			t.Error(fs, res)
+		}
+	}
+}
+
+func TestSniffFilesystem(t *testing.T) {
+	disk := make([]byte, 1024*1024*4)
+	fat := Partition{Type: GUID_EFI_SYSTEM, FirstLBA: 2048, LastLBA: 4095}
+	copy(disk[2048*512:], testFATImage()[:512])
+
+	ext4 := Partition{Type: GUID_LINUX_FS, FirstLBA: 4096, LastLBA: 8191}
+	superblock := disk[4096*512+1024:]
+	superblock[0x38], superblock[0x39] = 0x53, 0xEF
+	superblock[0x5C] = 0x4        // has_journal
+	superblock[0x60] = 0x40 | 0x2 // extents, filetype
+	superblock[0x61] = 0x2        // flex_bg
+
+	reader := bytes.NewReader(disk)
+	if fs, err := SniffFilesystem(reader, fat, 512); err != nil || fs != "FAT32" {
+		t.Error(fs, err)
+	}
+	if fs, err := SniffFilesystem(reader, ext4, 512); err != nil || fs != "ext4" {
+		t.Error(fs, err)
+	}
+
+	// Partition out of the image
+	unknown := Partition{Type: GUID_LINUX_FS, FirstLBA: 8000, LastLBA: 9000}
+	if fs, err := SniffFilesystem(reader, unknown, 512); err != nil || fs != "unknown" {
+		t.Error(fs, err)
+	}
+	if _, err := SniffFilesystem(reader, Partition{}, 512); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,113 @@
+package gpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownFlag = errors.New("Unknown partition flag")
+)
+
+// Bit numbers of partition attributes (Partition.Flags). Bits 48-63 are partition type specific.
+const (
+	FlagRequiredPartition  = 0
+	FlagNoBlockIOProtocol  = 1
+	FlagLegacyBIOSBootable = 2
+)
+
+// Attributes - partition attributes as number, bit N is value of attribute N.
+type Attributes uint64
+
+// Has - true if bit is set
+func (this Attributes) Has(bit uint) bool {
+	return bit < 64 && this&(1<<bit) != 0
+}
+
+// With - copy of the attributes with bit set to value
+func (this Attributes) With(bit uint, value bool) Attributes {
+	if value {
+		return this | 1<<bit
+	}
+	return this &^ (1 << bit)
+}
+
+func (this Attributes) RequiredPartition() bool {
+	return this.Has(FlagRequiredPartition)
+}
+
+func (this Attributes) NoBlockIOProtocol() bool {
+	return this.Has(FlagNoBlockIOProtocol)
+}
+
+func (this Attributes) LegacyBIOSBootable() bool {
+	return this.Has(FlagLegacyBIOSBootable)
+}
+
+// Attributes - Flags as number
+func (this Partition) Attributes() Attributes {
+	return Attributes(binary.LittleEndian.Uint64(this.Flags[:]))
+}
+
+// SetAttributes - store attributes to Flags
+func (this *Partition) SetAttributes(attributes Attributes) {
+	binary.LittleEndian.PutUint64(this.Flags[:], uint64(attributes))
+}
+
+var flagNames = map[uint]string{
+	FlagRequiredPartition:  "RequiredPartition",
+	FlagNoBlockIOProtocol:  "NoBlockIOProtocol",
+	FlagLegacyBIOSBootable: "LegacyBIOSBootable",
+}
+
+// FlagNames - names of set partition attribute bits. Unknown bits named as "bit N".
+func (this Partition) FlagNames() []string {
+	flags := this.Attributes()
+	res := make([]string, 0)
+	for bit := uint(0); bit < 64; bit++ {
+		if !flags.Has(bit) {
+			continue
+		}
+		if name, ok := flagNames[bit]; ok {
+			res = append(res, name)
+		} else {
+			res = append(res, fmt.Sprintf("bit %v", bit))
+		}
+	}
+	return res
+}
+
+// FlagsString - readable set of partition attributes, for example: "[RequiredPartition, LegacyBIOSBootable]"
+func (this Partition) FlagsString() string {
+	return "[" + strings.Join(this.FlagNames(), ", ") + "]"
+}
+
+// SetFlagNames - clear partition attributes and set bits by names. Names are same as returned by FlagNames,
+// include "bit N" for bits without name.
+func (this *Partition) SetFlagNames(names []string) error {
+	var flags Attributes
+	for _, name := range names {
+		bit, ok := flagBitByName(name)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownFlag, name)
+		}
+		flags = flags.With(bit, true)
+	}
+	this.SetAttributes(flags)
+	return nil
+}
+
+func flagBitByName(name string) (uint, bool) {
+	for bit, flagName := range flagNames {
+		if flagName == name {
+			return bit, true
+		}
+	}
+	var bit uint
+	if n, err := fmt.Sscanf(name, "bit %d", &bit); err == nil && n == 1 && bit < 64 && fmt.Sprintf("bit %v", bit) == name {
+		return bit, true
+	}
+	return 0, false
+}
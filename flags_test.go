@@ -0,0 +1,79 @@
+package gpt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlagNames(t *testing.T) {
+	var p Partition
+	if len(p.FlagNames()) != 0 || p.FlagsString() != "[]" {
+		t.Error(p.FlagNames(), p.FlagsString())
+	}
+
+	p.Flags[0] = 1<<FlagRequiredPartition | 1<<FlagLegacyBIOSBootable
+	if !reflect.DeepEqual(p.FlagNames(), []string{"RequiredPartition", "LegacyBIOSBootable"}) {
+		t.Error(p.FlagNames())
+	}
+	if p.FlagsString() != "[RequiredPartition, LegacyBIOSBootable]" {
+		t.Error(p.FlagsString())
+	}
+
+	p.Flags = Flags{}
+	p.Flags[6] = 1   // bit 48
+	p.Flags[7] = 128 // bit 63
+	if p.FlagsString() != "[bit 48, bit 63]" {
+		t.Error(p.FlagsString())
+	}
+}
+
+func TestSetFlagNames(t *testing.T) {
+	var p Partition
+	p.Flags[1] = 1
+	if err := p.SetFlagNames([]string{"RequiredPartition", "LegacyBIOSBootable"}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Flags != (Flags{5, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Error(p.Flags)
+	}
+
+	if err := p.SetFlagNames([]string{"NoBlockIOProtocol", "bit 60"}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Flags != (Flags{2, 0, 0, 0, 0, 0, 0, 16}) {
+		t.Error(p.Flags)
+	}
+
+	for _, name := range []string{"Unknown", "bit 64", "bit 1x", "bit  1"} {
+		if err := p.SetFlagNames([]string{name}); !errors.Is(err, ErrUnknownFlag) {
+			t.Error(name, err)
+		}
+	}
+	if p.Flags != (Flags{2, 0, 0, 0, 0, 0, 0, 16}) {
+		t.Error("Flags changed on error")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	if !Attributes(1).RequiredPartition() || Attributes(1).NoBlockIOProtocol() || Attributes(1).LegacyBIOSBootable() {
+		t.Error("Bad bit 0")
+	}
+	if !Attributes(4).LegacyBIOSBootable() || Attributes(0).Has(64) {
+		t.Error("Bad bits")
+	}
+
+	attributes := Attributes(0).With(FlagNoBlockIOProtocol, true).With(63, true)
+	if attributes != 1<<63|2 || attributes.With(63, false) != 2 {
+		t.Error(attributes)
+	}
+
+	var p Partition
+	p.SetAttributes(attributes)
+	if p.Flags != [8]byte{2, 0, 0, 0, 0, 0, 0, 0x80} || p.Attributes() != attributes {
+		t.Error(p.Flags, p.Attributes())
+	}
+	if p.FlagsString() != "[NoBlockIOProtocol, bit 63]" {
+		t.Error(p.FlagsString())
+	}
+}
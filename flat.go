@@ -0,0 +1,137 @@
+package gpt
+
+import (
+	"errors"
+	"fmt"
+)
+
+const maxFlatSectorSize = 64 * 1024       // Max sector size, accepted by FromFlat
+const maxFlatArrayBytes = 4 * 1024 * 1024 // Max size of partitions array, accepted by FromFlat
+
+var (
+	ErrBadFlatTable = errors.New("Bad flat table")
+)
+
+// FlatTable - the table with simple types only, for JSON and other wire formats. Guids are strings,
+// partitions list contains non-empty partitions only. Trailing bytes of header and entries aren't stored.
+type FlatTable struct {
+	SectorSize              uint64          `json:"sector_size"`
+	DiskGUID                string          `json:"disk_guid"`
+	Revision                uint32          `json:"revision"`
+	HeaderStartLBA          uint64          `json:"header_start_lba"`
+	HeaderCopyStartLBA      uint64          `json:"header_copy_start_lba"`
+	FirstUsableLBA          uint64          `json:"first_usable_lba"`
+	LastUsableLBA           uint64          `json:"last_usable_lba"`
+	PartitionsTableStartLBA uint64          `json:"partitions_table_start_lba"`
+	PartitionsArrLen        uint32          `json:"partitions_arr_len"`
+	PartitionEntrySize      uint32          `json:"partition_entry_size"`
+	Partitions              []FlatPartition `json:"partitions"`
+}
+
+// FlatPartition - partition of FlatTable
+type FlatPartition struct {
+	Index    int      `json:"index"` // Slot in partitions array
+	Type     string   `json:"type"`
+	Id       string   `json:"id"`
+	FirstLBA uint64   `json:"first_lba"`
+	LastLBA  uint64   `json:"last_lba"`
+	Name     string   `json:"name"`
+	Flags    []string `json:"flags"` // Names of set attributes, see Partition.FlagNames
+}
+
+// ToFlat - convert the table to FlatTable
+func (this Table) ToFlat() FlatTable {
+	res := FlatTable{
+		SectorSize:              this.SectorSize,
+		DiskGUID:                this.Header.DiskGUID.String(),
+		Revision:                this.Header.Revision,
+		HeaderStartLBA:          this.Header.HeaderStartLBA,
+		HeaderCopyStartLBA:      this.Header.HeaderCopyStartLBA,
+		FirstUsableLBA:          this.Header.FirstUsableLBA,
+		LastUsableLBA:           this.Header.LastUsableLBA,
+		PartitionsTableStartLBA: this.Header.PartitionsTableStartLBA,
+		PartitionsArrLen:        this.Header.PartitionsArrLen,
+		PartitionEntrySize:      this.Header.PartitionEntrySize,
+		Partitions:              make([]FlatPartition, 0),
+	}
+	for i, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		res.Partitions = append(res.Partitions, FlatPartition{
+			Index:    i,
+			Type:     p.Type.String(),
+			Id:       p.Id.String(),
+			FirstLBA: p.FirstLBA,
+			LastLBA:  p.LastLBA,
+			Name:     p.Name(),
+			Flags:    p.FlagNames(),
+		})
+	}
+	return res
+}
+
+// FromFlat - create table from FlatTable, CRCs are calculated. Sizes of the table are checked before allocate memory:
+// sector size must be power of two from 512 to 64KiB, partition entry size - multiple of 8 from 128 bytes,
+// size of partitions array is limited by 4MiB.
+func FromFlat(flat FlatTable) (Table, error) {
+	if flat.SectorSize < backupBlockSize || flat.SectorSize > maxFlatSectorSize || flat.SectorSize&(flat.SectorSize-1) != 0 {
+		return Table{}, fmt.Errorf("%w: sector size %v", ErrBadFlatTable, flat.SectorSize)
+	}
+	if flat.PartitionEntrySize < standardPartitionEntrySize || flat.PartitionEntrySize%8 != 0 {
+		return Table{}, fmt.Errorf("%w: partition entry size %v", ErrBadFlatTable, flat.PartitionEntrySize)
+	}
+	if uint64(flat.PartitionsArrLen)*uint64(flat.PartitionEntrySize) > maxFlatArrayBytes {
+		return Table{}, fmt.Errorf("%w: partitions array of %v entries by %v bytes is more then %v bytes", ErrBadFlatTable,
+			flat.PartitionsArrLen, flat.PartitionEntrySize, maxFlatArrayBytes)
+	}
+	diskGuid, err := StringToGuid(flat.DiskGUID)
+	if err != nil {
+		return Table{}, err
+	}
+	table := Table{
+		SectorSize: flat.SectorSize,
+		Header: Header{
+			Revision:                flat.Revision,
+			Size:                    standardHeaderSize,
+			HeaderStartLBA:          flat.HeaderStartLBA,
+			HeaderCopyStartLBA:      flat.HeaderCopyStartLBA,
+			FirstUsableLBA:          flat.FirstUsableLBA,
+			LastUsableLBA:           flat.LastUsableLBA,
+			DiskGUID:                diskGuid,
+			PartitionsTableStartLBA: flat.PartitionsTableStartLBA,
+			PartitionsArrLen:        flat.PartitionsArrLen,
+			PartitionEntrySize:      flat.PartitionEntrySize,
+			TrailingBytes:           make([]byte, flat.SectorSize-standardHeaderSize),
+		},
+		Partitions: make([]Partition, flat.PartitionsArrLen),
+	}
+	copy(table.Header.Signature[:], gptSignature)
+	for i := range table.Partitions {
+		table.Partitions[i].TrailingBytes = table.entryTrailingBytes(nil)
+	}
+
+	for _, flatPart := range flat.Partitions {
+		if err = table.checkIndex(flatPart.Index); err != nil {
+			return Table{}, err
+		}
+		p := &table.Partitions[flatPart.Index]
+		var typ, id [16]byte
+		if typ, err = StringToGuid(flatPart.Type); err != nil {
+			return Table{}, fmt.Errorf("partition %v: %w", flatPart.Index, err)
+		}
+		if id, err = StringToGuid(flatPart.Id); err != nil {
+			return Table{}, fmt.Errorf("partition %v: %w", flatPart.Index, err)
+		}
+		p.Type, p.Id = PartType(typ), Guid(id)
+		p.FirstLBA, p.LastLBA = flatPart.FirstLBA, flatPart.LastLBA
+		if err = p.SetName(flatPart.Name); err != nil {
+			return Table{}, fmt.Errorf("partition %v: %w", flatPart.Index, err)
+		}
+		if err = p.SetFlagNames(flatPart.Flags); err != nil {
+			return Table{}, fmt.Errorf("partition %v: %w", flatPart.Index, err)
+		}
+	}
+	table.Touch()
+	return table, nil
+}
@@ -0,0 +1,65 @@
+package gpt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFlatTable(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].SetAttributes(Attributes(0).With(FlagRequiredPartition, true).With(60, true))
+	table.Touch()
+
+	flat := table.ToFlat()
+	if len(flat.Partitions) != 3 || flat.Partitions[2].Index != 2 || flat.Partitions[2].Name != "primary" {
+		t.Fatal(flat.Partitions)
+	}
+	esp := flat.Partitions[0]
+	if esp.Type != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" || esp.Id != "DC2F50B0-98DE-4681-A868-42E9FEBD6E3E" ||
+		esp.FirstLBA != 2048 || esp.LastLBA != 780287 {
+		t.Error(esp)
+	}
+
+	data, err := json.Marshal(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded FlatTable
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	res, err := FromFlat(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := table.PartitionArrayBytes()
+	if array, _ := res.PartitionArrayBytes(); !bytes.Equal(array, expected) {
+		t.Error("Partitions differ")
+	}
+	if !HeadersEqual(res.Header, table.Header) {
+		t.Error("Headers differ: ", res.Header, table.Header)
+	}
+
+	decoded.Partitions[0].Index = 128
+	if _, err = FromFlat(decoded); err == nil {
+		t.Error("Bad index")
+	}
+
+	for _, modify := range []func(f *FlatTable){
+		func(f *FlatTable) { f.SectorSize = 1 << 62 },
+		func(f *FlatTable) { f.SectorSize = 1000 },
+		func(f *FlatTable) { f.SectorSize = 256 },
+		func(f *FlatTable) { f.PartitionEntrySize = 0xFFFFFFFF },
+		func(f *FlatTable) { f.PartitionEntrySize = 100 },
+		func(f *FlatTable) { f.PartitionsArrLen = 0xFFFFFFFF },
+	} {
+		bad := table.ToFlat()
+		modify(&bad)
+		if _, err = FromFlat(bad); !errors.Is(err, ErrBadFlatTable) {
+			t.Error(bad.SectorSize, bad.PartitionEntrySize, bad.PartitionsArrLen, err)
+		}
+	}
+}
@@ -4,14 +4,31 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
+	"math"
+	mathrand "math/rand"
 	"unicode/utf16"
 )
 
 const standardHeaderSize = 92          // Size of standard GPT-header in bytes
 const standardPartitionEntrySize = 128 // Size of standard GPT-partition entry in bytes
+const standardPartitionsArrLen = 128   // Minimal count of entries in partitions array by UEFI spec
+const gptSignature = "EFI PART"
+
+var (
+	ErrBadSignature           = errors.New("Bad GPT signature")
+	ErrBadHeaderCRC           = errors.New("BAD GPT Header CRC")
+	ErrBadPartitionsCRC       = errors.New("Bad partitions crc")
+	ErrPartitionCountMismatch = errors.New("Partitions count doesn't match Header.PartitionsArrLen")
+	ErrNameTooLong            = errors.New("Partition name is too long")
+	ErrPartitionBeforeUsable  = errors.New("Partition is out of usable LBA range")
+	ErrTruncatedArray         = errors.New("Partitions array is truncated")
+	ErrWriteOutOfDisk         = errors.New("Write position is out of disk")
+	ErrUnsupportedRevision    = errors.New("Unsupported GPT revision")
+)
 
 type Flags [8]byte
 type Guid [16]byte
@@ -20,16 +37,68 @@ func (this Guid) String() string {
 	return guidToString(this)
 }
 
+// Version - version of the guid by RFC 4122, for example 4 for random guid. Version is in high nibble
+// of third group, which is stored in little-endian.
+func (this Guid) Version() int {
+	return int(this[7] >> 4)
+}
+
+// Variant - layout of the guid: "NCS", "RFC4122", "Microsoft" or "Future".
+func (this Guid) Variant() string {
+	switch {
+	case this[8]&0x80 == 0:
+		return "NCS"
+	case this[8]&0x40 == 0:
+		return "RFC4122"
+	case this[8]&0x20 == 0:
+		return "Microsoft"
+	default:
+		return "Future"
+	}
+}
+
+// GuidFromBytes - convert raw bytes (in on-disk order) to Guid.
+func GuidFromBytes(b [16]byte) Guid {
+	return Guid(b)
+}
+
+// Bytes - raw bytes of the guid in on-disk order.
+func (this Guid) Bytes() [16]byte {
+	return this
+}
+
+// ParseGUIDRaw - make Guid from raw bytes. mixedEndian - bytes are stored as in GPT specification (first three
+// groups in little-endian), else whole guid is stored in big-endian: in same order as in string.
+func ParseGUIDRaw(b [16]byte, mixedEndian bool) Guid {
+	if mixedEndian {
+		return Guid(b)
+	}
+	return swapGuidEndian(b)
+}
+
+// FormatGUID - string of the guid. mixedEndian - as String(), else the guid bytes are printed in stored order,
+// as if it was saved in big-endian.
+func FormatGUID(g Guid, mixedEndian bool) string {
+	if mixedEndian {
+		return guidToString(g)
+	}
+	return guidToString(swapGuidEndian(g))
+}
+
+// Convert mixed-endian guid bytes to big-endian and back: reverse bytes of first three groups.
+func swapGuidEndian(b [16]byte) [16]byte {
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+	return b
+}
+
 type PartType Guid
 
 func (this PartType) String() string {
 	return guidToString(this)
 }
 
-var (
-	GUID_LVM = PartType([16]byte{0x79, 0xd3, 0xd6, 0xe6, 0x7, 0xf5, 0xc2, 0x44, 0xa2, 0x3c, 0x23, 0x8f, 0x2a, 0x3d, 0xf9, 0x28}) // E6D6D379-F507-44C2-A23C-238F2A3DF928
-)
-
 // https://en.wikipedia.org/wiki/GUID_Partition_Table#Partition_table_header_.28LBA_1.29
 type Header struct {
 	Signature               [8]byte // Offset  0. "EFI PART", 45h 46h 49h 20h 50h 41h 52h 54h
@@ -72,54 +141,131 @@ type Table struct {
 
 // Have to set to start of Header. Usually LBA1 for primary header.
 func readHeader(reader io.Reader, sectorSize uint64) (res Header, err error) {
-	read := func(data interface{}) {
-		if err == nil {
-			err = binary.Read(reader, binary.LittleEndian, data)
-		}
-	}
-
-	read(&res.Signature)
-	read(&res.Revision)
-	read(&res.Size)
-	read(&res.CRC)
-	read(&res.Reserved)
-	read(&res.HeaderStartLBA)
-	read(&res.HeaderCopyStartLBA)
-	read(&res.FirstUsableLBA)
-	read(&res.LastUsableLBA)
-	read(&res.DiskGUID)
-	read(&res.PartitionsTableStartLBA)
-	read(&res.PartitionsArrLen)
-	read(&res.PartitionEntrySize)
-	read(&res.PartitionsCRC)
+	return readHeaderByteOrder(reader, sectorSize, binary.LittleEndian)
+}
+
+// Read header with fields in the byte order. CRC is checked for little endian form of the header.
+func readHeaderByteOrder(reader io.Reader, sectorSize uint64, order binary.ByteOrder) (res Header, err error) {
+	read := readFieldsFunc(reader, order, "header", &err)
+
+	read("Signature", &res.Signature)
+	read("Revision", &res.Revision)
+	read("Size", &res.Size)
+	read("CRC", &res.CRC)
+	read("Reserved", &res.Reserved)
+	read("HeaderStartLBA", &res.HeaderStartLBA)
+	read("HeaderCopyStartLBA", &res.HeaderCopyStartLBA)
+	read("FirstUsableLBA", &res.FirstUsableLBA)
+	read("LastUsableLBA", &res.LastUsableLBA)
+	read("DiskGUID", &res.DiskGUID)
+	read("PartitionsTableStartLBA", &res.PartitionsTableStartLBA)
+	read("PartitionsArrLen", &res.PartitionsArrLen)
+	read("PartitionEntrySize", &res.PartitionEntrySize)
+	read("PartitionsCRC", &res.PartitionsCRC)
 	if err != nil {
 		return
 	}
 
-	if string(res.Signature[:]) != "EFI PART" {
-		return res, fmt.Errorf("Bad GPT signature")
+	if string(res.Signature[:]) != gptSignature {
+		return res, ErrBadSignature
 	}
 	trailingBytes := make([]byte, sectorSize-uint64(standardHeaderSize))
-	reader.Read(trailingBytes)
+	_, err = io.ReadFull(reader, trailingBytes)
+	if err != nil {
+		return res, fmt.Errorf("read header trailing bytes at offset %v: %w", standardHeaderSize, err)
+	}
 	res.TrailingBytes = trailingBytes
 
 	if res.calcCRC() != res.CRC {
-		return res, fmt.Errorf("BAD GPT Header CRC")
+		return res, ErrBadHeaderCRC
 	}
 
 	return
 }
 
+// BackupHeader - create backup copy of primary header for the disk: header in the last sector
+// and partitions array of entryArraySectors sectors right before it. CRC is recalculated.
+func (this Header) BackupHeader(diskSizeBytes uint64, sectorSize uint64, entryArraySectors uint64) Header {
+	res := this
+	res.TrailingBytes = make([]byte, len(this.TrailingBytes))
+	copy(res.TrailingBytes, this.TrailingBytes)
+
+	res.HeaderStartLBA = diskSizeBytes/sectorSize - 1
+	res.HeaderCopyStartLBA = this.HeaderStartLBA
+	res.PartitionsTableStartLBA = res.HeaderStartLBA - entryArraySectors
+	res.CRC = res.calcCRC()
+	return res
+}
+
+// Count of sectors for store partitions array
+func (this Header) partitionsTableSectors(sectorSize uint64) uint64 {
+	partitionsTableSize := uint64(this.PartitionEntrySize) * uint64(this.PartitionsArrLen)
+	res := partitionsTableSize / sectorSize
+	if partitionsTableSize%sectorSize != 0 {
+		res++
+	}
+	return res
+}
+
+// HeadersEqual - compare all fields of the headers, include TrailingBytes.
+func HeadersEqual(a, b Header) bool {
+	return a.Signature == b.Signature &&
+		a.Revision == b.Revision &&
+		a.Size == b.Size &&
+		a.CRC == b.CRC &&
+		a.Reserved == b.Reserved &&
+		a.HeaderStartLBA == b.HeaderStartLBA &&
+		a.HeaderCopyStartLBA == b.HeaderCopyStartLBA &&
+		a.FirstUsableLBA == b.FirstUsableLBA &&
+		a.LastUsableLBA == b.LastUsableLBA &&
+		a.DiskGUID == b.DiskGUID &&
+		a.PartitionsTableStartLBA == b.PartitionsTableStartLBA &&
+		a.PartitionsArrLen == b.PartitionsArrLen &&
+		a.PartitionEntrySize == b.PartitionEntrySize &&
+		a.PartitionsCRC == b.PartitionsCRC &&
+		bytes.Equal(a.TrailingBytes, b.TrailingBytes)
+}
+
+// AreClones - true if the tables have same disk guid and same partition guids in same slots.
+// Other fields of partitions aren't compared.
+func AreClones(a, b Table) bool {
+	if a.Header.DiskGUID != b.Header.DiskGUID {
+		return false
+	}
+	var emptyGuid Guid
+	partitionId := func(t Table, index int) Guid {
+		if index < len(t.Partitions) && !t.Partitions[index].IsEmpty() {
+			return t.Partitions[index].Id
+		}
+		return emptyGuid
+	}
+	for i := 0; i < len(a.Partitions) || i < len(b.Partitions); i++ {
+		if partitionId(a, i) != partitionId(b, i) {
+			return false
+		}
+	}
+	return true
+}
+
 func (this *Header) calcCRC() uint32 {
 	buf := &bytes.Buffer{}
 	this.write(buf, false)
+	if int(this.Size) > buf.Len() {
+		// Corrupted header, real size can't be more then sector size
+		return crc32.ChecksumIEEE(buf.Bytes())
+	}
 	return crc32.ChecksumIEEE(buf.Bytes()[:this.Size])
 }
 
 func (this *Header) write(writer io.Writer, saveCRC bool) (err error) {
+	return this.writeByteOrder(writer, saveCRC, binary.LittleEndian)
+}
+
+// Write header with fields in the byte order. CRC is calculated for little endian form of the header.
+func (this *Header) writeByteOrder(writer io.Writer, saveCRC bool, order binary.ByteOrder) (err error) {
 	write := func(data interface{}) {
 		if err == nil {
-			err = binary.Write(writer, binary.LittleEndian, data)
+			err = binary.Write(writer, order, data)
 		}
 	}
 
@@ -155,29 +301,51 @@ func (this *Header) write(writer io.Writer, saveCRC bool) (err error) {
 ///////////////// PARTITION //////////////////
 //////////////////////////////////////////////
 func readPartition(reader io.Reader, size uint32) (p Partition, err error) {
-	read := func(data interface{}) {
-		if err == nil {
-			err = binary.Read(reader, binary.LittleEndian, data)
-		}
+	return readPartitionByteOrder(reader, size, binary.LittleEndian)
+}
+
+func readPartitionByteOrder(reader io.Reader, size uint32, order binary.ByteOrder) (p Partition, err error) {
+	if size < standardPartitionEntrySize {
+		return p, fmt.Errorf("%w: %v, it must be at least %v", ErrBadEntrySize, size, standardPartitionEntrySize)
 	}
+	read := readFieldsFunc(reader, order, "partition entry", &err)
 
 	p.TrailingBytes = make([]byte, size-standardPartitionEntrySize)
 
-	read(&p.Type)
-	read(&p.Id)
-	read(&p.FirstLBA)
-	read(&p.LastLBA)
-	read(&p.Flags)
-	read(&p.PartNameUTF16)
-	read(&p.TrailingBytes)
+	read("Type", &p.Type)
+	read("Id", &p.Id)
+	read("FirstLBA", &p.FirstLBA)
+	read("LastLBA", &p.LastLBA)
+	read("Flags", &p.Flags)
+	read("PartNameUTF16", &p.PartNameUTF16)
+	read("TrailingBytes", &p.TrailingBytes)
 
 	return
 }
 
+// Return function, which read fields of structure one by one. First error is saved to err and wrapped
+// with field name and its offset in the structure, fields after error aren't read.
+func readFieldsFunc(reader io.Reader, order binary.ByteOrder, structName string, err *error) func(name string, data interface{}) {
+	offset := 0
+	return func(name string, data interface{}) {
+		if *err != nil {
+			return
+		}
+		if readErr := binary.Read(reader, order, data); readErr != nil {
+			*err = fmt.Errorf("read %v field %v at offset %v: %w", structName, name, offset, readErr)
+		}
+		offset += binary.Size(data)
+	}
+}
+
 func (this Partition) write(writer io.Writer, size uint32) (err error) {
+	return this.writeByteOrder(writer, size, binary.LittleEndian)
+}
+
+func (this Partition) writeByteOrder(writer io.Writer, size uint32, order binary.ByteOrder) (err error) {
 	write := func(data interface{}) {
 		if err == nil {
-			err = binary.Write(writer, binary.LittleEndian, data)
+			err = binary.Write(writer, order, data)
 		}
 	}
 
@@ -200,6 +368,19 @@ func (this Partition) IsEmpty() bool {
 	return this.Type == [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 }
 
+// Count of sectors from FirstLBA to LastLBA, include both.
+func (this Partition) SizeInSectors() uint64 {
+	if this.LastLBA < this.FirstLBA {
+		return 0
+	}
+	return this.LastLBA - this.FirstLBA + 1
+}
+
+// Check if LBA ranges of partitions intersect
+func (this Partition) overlaps(other Partition) bool {
+	return this.FirstLBA <= other.LastLBA && other.FirstLBA <= this.LastLBA
+}
+
 func (this Partition) Name() string {
 	chars := make([]uint16, 0, 36)
 	for i := 0; i < len(this.PartNameUTF16); i += 2 {
@@ -214,55 +395,242 @@ func (this Partition) Name() string {
 	return string(runes)
 }
 
+// HasNonZeroReserved - true if bytes, which aren't used by entry fields, aren't zero: TrailingBytes of entry and
+// part of name after terminating zero.
+func (this Partition) HasNonZeroReserved() bool {
+	terminated := false
+	for i := 0; i < len(this.PartNameUTF16); i += 2 {
+		zero := this.PartNameUTF16[i] == 0 && this.PartNameUTF16[i+1] == 0
+		if terminated && !zero {
+			return true
+		}
+		terminated = terminated || zero
+	}
+	for _, b := range this.TrailingBytes {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxNameRunes - max length of partition name in UTF-16 code units. Terminating zero isn't needed for name of max length.
+// Characters out of Basic Multilingual Plane (emoji, for example) take two code units.
+func (this Partition) MaxNameRunes() int {
+	return len(this.PartNameUTF16) / 2
+}
+
+// NameFits - check if name can be saved as partition name.
+func (this Partition) NameFits(name string) bool {
+	return len(utf16.Encode([]rune(name))) <= this.MaxNameRunes()
+}
+
+// SetName - save name as partition name. Return ErrNameTooLong if the name doesn't fit.
+func (this *Partition) SetName(name string) error {
+	if !this.NameFits(name) {
+		return fmt.Errorf("%w: %q", ErrNameTooLong, name)
+	}
+	this.PartNameUTF16 = [72]byte{}
+	for i, char := range utf16.Encode([]rune(name)) {
+		this.PartNameUTF16[i*2] = byte(char)
+		this.PartNameUTF16[i*2+1] = byte(char >> 8)
+	}
+	return nil
+}
+
 //////////////////////////////////////////////
 ////////////////// TABLE /////////////////////
 //////////////////////////////////////////////
 
+// ReadOptions - options for ReadTableWithOptions. Nil options mean defaults.
+type ReadOptions struct {
+	SkipPartitionCRC      bool // Read partitions, but don't calculate and check partitions CRC
+	RejectUnknownRevision bool // Return ErrUnsupportedRevision if major version of Header.Revision more then 1
+
+	// Accept partitions CRC, calculated over non-empty entries only, as some firmware does.
+	// It is checked if CRC over whole array is bad.
+	UsedEntriesCRCFallback bool
+
+	// Byte order of header and partition entries fields, nil means binary.LittleEndian as in GPT specification.
+	// For experiments with dumps from exotic hardware. CRCs are checked for little endian form of the table.
+	ByteOrder binary.ByteOrder
+}
+
 // Read GPT partition
 // Have to set to first byte of GPT Header (usually start of second sector on disk)
 func ReadTable(reader io.ReadSeeker, SectorSize uint64) (table Table, err error) {
-	table.SectorSize = SectorSize
-	table.Header, err = readHeader(reader, SectorSize)
+	return ReadTableWithOptions(reader, SectorSize, nil)
+}
+
+// ReadTableWithOptions - same as ReadTable, with tuned checks.
+func ReadTableWithOptions(reader io.ReadSeeker, SectorSize uint64, options *ReadOptions) (table Table, err error) {
+	table, res, err := readTable(reader, SectorSize, options, true)
 	if err != nil {
 		return
 	}
-	if seekDest, ok := mul(int64(SectorSize), int64(table.Header.PartitionsTableStartLBA)); ok {
-		reader.Seek(seekDest, 0)
-	} else {
-		err = fmt.Errorf("Seek overflow when read partition tables")
+	if !options.skipPartitionCRC() && !res.PartitionsCRCValid {
+		return table, ErrBadPartitionsCRC
+	}
+	return
+}
+
+// Source of read table
+type TableSource int
+
+const (
+	SourcePrimary TableSource = iota // Primary table, usually from LBA1
+	SourceBackup                     // Backup table from the end of disk
+)
+
+// ReadResult - verification status of read table
+type ReadResult struct {
+	HeaderCRCValid     bool
+	PartitionsCRCValid bool // Always false if the check skipped by ReadOptions.SkipPartitionCRC
+
+	// Partitions CRC match non-empty entries only, see ReadOptions.UsedEntriesCRCFallback
+	PartitionsCRCUsedEntries bool
+	Source                   TableSource
+}
+
+// ReadTableVerbose - read table and report about CRC checks instead of fail on them.
+// Return error for read problems and bad signature only.
+// Partitions aren't read if header CRC is bad: place and size of partitions array in the header are untrusted.
+func ReadTableVerbose(reader io.ReadSeeker, SectorSize uint64, options *ReadOptions) (Table, ReadResult, error) {
+	return readTable(reader, SectorSize, options, false)
+}
+
+// Read table, return ErrBadHeaderCRC for bad header if requireHeaderCRC or skip partitions read if not.
+func readTable(reader io.ReadSeeker, SectorSize uint64, options *ReadOptions, requireHeaderCRC bool) (table Table, res ReadResult, err error) {
+	if options == nil {
+		options = &ReadOptions{}
+	}
+	table.SectorSize = SectorSize
+	table.Header, err = readHeaderByteOrder(reader, SectorSize, options.byteOrder())
+	if errors.Is(err, ErrBadSignature) {
+		if fs := sniffImageStart(reader); fs != "" {
+			err = fmt.Errorf("%w: %v filesystem at start of the image", ErrLooksLikeFilesystemImage, fs)
+		}
+	}
+	res.HeaderCRCValid = err == nil
+	if errors.Is(err, ErrBadHeaderCRC) {
+		err = nil
+	}
+	if err != nil {
 		return
 	}
-	for i := uint32(0); i < table.Header.PartitionsArrLen; i++ {
-		var p Partition
-		p, err = readPartition(reader, table.Header.PartitionEntrySize)
-		if err != nil {
-			return
+	if table.IsBackupHeader() {
+		res.Source = SourceBackup
+	}
+	if !res.HeaderCRCValid {
+		if requireHeaderCRC {
+			err = ErrBadHeaderCRC
 		}
-		table.Partitions = append(table.Partitions, p)
+		return
+	}
+	if options.RejectUnknownRevision && table.Header.Revision>>16 > 1 {
+		return table, res, fmt.Errorf("%w: %#08x", ErrUnsupportedRevision, table.Header.Revision)
 	}
 
-	if table.Header.PartitionsCRC != table.calcPartitionsCRC() {
-		err = fmt.Errorf("Bad partitions crc")
+	table.Partitions, err = readPartitionArrayByteOrder(reader, SectorSize, table.Header.PartitionsTableStartLBA,
+		table.Header.PartitionsArrLen, table.Header.PartitionEntrySize, options.byteOrder())
+	if err != nil {
 		return
 	}
+
+	if !options.SkipPartitionCRC {
+		res.PartitionsCRCValid = table.Header.PartitionsCRC == table.calcPartitionsCRC()
+		if !res.PartitionsCRCValid && options.UsedEntriesCRCFallback && table.Header.PartitionsCRC == table.calcUsedPartitionsCRC() {
+			res.PartitionsCRCValid = true
+			res.PartitionsCRCUsedEntries = true
+		}
+	}
 	return
 }
 
-func (this Table) CreateOtherSideTable() (res Table) {
-	res = this.copy()
+func (this *ReadOptions) skipPartitionCRC() bool {
+	return this != nil && this.SkipPartitionCRC
+}
 
-	// Copy of table on other side of disk
-	tmpDest := res.Header.HeaderStartLBA
-	res.Header.HeaderStartLBA = res.Header.HeaderCopyStartLBA
-	res.Header.HeaderCopyStartLBA = tmpDest
+func (this *ReadOptions) byteOrder() binary.ByteOrder {
+	if this == nil || this.ByteOrder == nil {
+		return binary.LittleEndian
+	}
+	return this.ByteOrder
+}
 
-	if res.Header.HeaderStartLBA == 1 {
-		res.Header.PartitionsTableStartLBA = 2
+// PrimaryHeaderLBA - LBA of primary header, independent of which copy the table is.
+func (this Table) PrimaryHeaderLBA() uint64 {
+	if this.IsBackupHeader() {
+		return this.Header.HeaderCopyStartLBA
+	}
+	return this.Header.HeaderStartLBA
+}
+
+// BackupHeaderLBA - LBA of backup header, independent of which copy the table is.
+func (this Table) BackupHeaderLBA() uint64 {
+	if this.IsBackupHeader() {
+		return this.Header.HeaderStartLBA
+	}
+	return this.Header.HeaderCopyStartLBA
+}
+
+// IsBackupHeader - true if the table is backup copy (it was read from the end of disk).
+func (this Table) IsBackupHeader() bool {
+	return this.Header.HeaderStartLBA > this.Header.HeaderCopyStartLBA
+}
+
+// HasStandardLayout - true if the table is primary table with header at LBA 1, partitions array at LBA 2
+// of 128 entries by 128 bytes. Most tools expect the layout, other disks are valid but worth warn.
+func (this Table) HasStandardLayout() bool {
+	return this.Header.HeaderStartLBA == 1 && this.Header.PartitionsTableStartLBA == 2 &&
+		this.Header.PartitionsArrLen == standardPartitionsArrLen && this.Header.PartitionEntrySize == standardPartitionEntrySize
+}
+
+// Read count partition entries from arrayStartLBA. Work for primary and backup partitions array.
+// If the array is truncated - return read entries and ErrTruncatedArray.
+func readPartitionArray(reader io.ReadSeeker, sectorSize uint64, arrayStartLBA uint64, count, entrySize uint32) (res []Partition, err error) {
+	return readPartitionArrayByteOrder(reader, sectorSize, arrayStartLBA, count, entrySize, binary.LittleEndian)
+}
+
+func readPartitionArrayByteOrder(reader io.ReadSeeker, sectorSize uint64, arrayStartLBA uint64, count, entrySize uint32,
+	order binary.ByteOrder) (res []Partition, err error) {
+	if seekDest, ok := mul(int64(sectorSize), int64(arrayStartLBA)); ok {
+		_, err = reader.Seek(seekDest, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		// Partitions table on other side of disk
-		res.Header.PartitionsTableStartLBA = res.Header.LastUsableLBA + 1
+		return nil, fmt.Errorf("Seek overflow when read partition tables")
+	}
+	// count is read from disk and can be broken, so res grows by append only
+	for i := uint32(0); i < count; i++ {
+		var p Partition
+		p, err = readPartitionByteOrder(reader, entrySize, order)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return res, fmt.Errorf("%w: read %v of %v entries", ErrTruncatedArray, len(res), count)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func (this Table) CreateOtherSideTable() (res Table) {
+	res = this.copy()
+
+	if !this.IsBackupHeader() {
+		// Backup header in last sector of the disk, right after its partitions array
+		res.Header = this.Header.BackupHeader((this.Header.HeaderCopyStartLBA+1)*this.SectorSize, this.SectorSize,
+			this.Header.partitionsTableSectors(this.SectorSize))
+		return res
 	}
 
+	// Primary table from backup
+	res.Header.HeaderStartLBA = this.Header.HeaderCopyStartLBA
+	res.Header.HeaderCopyStartLBA = this.Header.HeaderStartLBA
+	res.Header.PartitionsTableStartLBA = 2
 	res.Header.CRC = res.Header.calcCRC()
 	return res
 }
@@ -277,11 +645,7 @@ func (this Table) CreateTableForNewDiskSize(size uint64) (res Table) {
 	res.Header.PartitionsTableStartLBA = 2
 	res.Header.HeaderCopyStartLBA = size - 1 // Last sector
 
-	partitionsTableSize := uint64(res.Header.PartitionEntrySize) * uint64(res.Header.PartitionsArrLen)
-	partitionSizeInSector := partitionsTableSize / uint64(res.SectorSize)
-	if partitionsTableSize%uint64(res.SectorSize) != 0 {
-		partitionSizeInSector++
-	}
+	partitionSizeInSector := res.Header.partitionsTableSectors(res.SectorSize)
 	res.Header.LastUsableLBA = size - 1 - partitionSizeInSector - 1 // header in last sector and partitions table
 
 	res.Header.CRC = res.Header.calcCRC()
@@ -312,14 +676,94 @@ func (this Table) calcPartitionsCRC() uint32 {
 	return crc32.ChecksumIEEE(buf.Bytes())
 }
 
+// CRC of non-empty partition entries only
+func (this Table) calcUsedPartitionsCRC() uint32 {
+	buf := &bytes.Buffer{}
+	for _, part := range this.Partitions {
+		if !part.IsEmpty() {
+			part.write(buf, this.Header.PartitionEntrySize)
+		}
+	}
+	return crc32.ChecksumIEEE(buf.Bytes())
+}
+
+// PartitionArrayBytes - serialized partitions array as it is stored on disk: PartitionsArrLen entries
+// of PartitionEntrySize bytes. Missed entries are padded by empty entries.
+// Partitions CRC calculated over this bytes.
+func (this Table) PartitionArrayBytes() ([]byte, error) {
+	if uint32(len(this.Partitions)) > this.Header.PartitionsArrLen {
+		return nil, fmt.Errorf("%w: %v partitions, PartitionsArrLen %v", ErrPartitionCountMismatch, len(this.Partitions), this.Header.PartitionsArrLen)
+	}
+	if this.Header.PartitionEntrySize < standardPartitionEntrySize {
+		return nil, fmt.Errorf("Partition entry size(%v) less then standard(%v)", this.Header.PartitionEntrySize, standardPartitionEntrySize)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, uint64(this.Header.PartitionsArrLen)*uint64(this.Header.PartitionEntrySize)))
+	for _, part := range this.Partitions {
+		if err := part.write(buf, this.Header.PartitionEntrySize); err != nil {
+			return nil, err
+		}
+	}
+	empty := Partition{TrailingBytes: make([]byte, this.Header.PartitionEntrySize-standardPartitionEntrySize)}
+	for i := uint32(len(this.Partitions)); i < this.Header.PartitionsArrLen; i++ {
+		empty.write(buf, this.Header.PartitionEntrySize)
+	}
+	return buf.Bytes(), nil
+}
+
+// PartitionEntryBytes - serialized entry of partition with index as it is stored on disk, with trailing bytes.
+func (this Table) PartitionEntryBytes(index int) ([]byte, error) {
+	if index < 0 || index >= len(this.Partitions) {
+		return nil, fmt.Errorf("%w: %v", ErrBadPartitionIndex, index)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, this.Header.PartitionEntrySize))
+	if err := this.Partitions[index].write(buf, this.Header.PartitionEntrySize); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PartitionsByName - indexes of non-empty partitions with non-empty names by the names.
+// If some partitions have same name - last of them is in the map.
+func (this Table) PartitionsByName() map[string]int {
+	res := make(map[string]int)
+	for i, p := range this.Partitions {
+		if name := p.Name(); !p.IsEmpty() && name != "" {
+			res[name] = i
+		}
+	}
+	return res
+}
+
+// Return index pairs of non-empty partitions with intersected LBA ranges
+func (this Table) overlappedPartitions() (res [][2]int) {
+	for i := range this.Partitions {
+		if this.Partitions[i].IsEmpty() {
+			continue
+		}
+		for j := i + 1; j < len(this.Partitions); j++ {
+			if !this.Partitions[j].IsEmpty() && this.Partitions[i].overlaps(this.Partitions[j]) {
+				res = append(res, [2]int{i, j})
+			}
+		}
+	}
+	return res
+}
+
 // Calc header and partitions CRC. Save Header and partition entries to the disk.
 // It independent of start position: writer will be seek to position from Table.Header.
+// CRCs are calculated for written copy only, the table isn't changed. Use Touch for update CRCs in the table.
 func (this Table) Write(writer io.WriteSeeker) (err error) {
+	return this.WriteByteOrder(writer, binary.LittleEndian)
+}
+
+// WriteByteOrder - same as Write, but numeric fields of header and partition entries are written in the byte order.
+// For experiments only, GPT specification require little endian. CRCs are calculated for little endian form of the table.
+func (this Table) WriteByteOrder(writer io.WriteSeeker, order binary.ByteOrder) (err error) {
 	this.Header.PartitionsCRC = this.calcPartitionsCRC()
 	if headerPos, ok := mul(int64(this.SectorSize), int64(this.Header.HeaderStartLBA)); ok {
 		writer.Seek(headerPos, 0)
 	}
-	err = this.Header.write(writer, true)
+	err = this.Header.writeByteOrder(writer, true, order)
 	if err != nil {
 		return
 	}
@@ -327,7 +771,7 @@ func (this Table) Write(writer io.WriteSeeker) (err error) {
 		writer.Seek(partTablePos, 0)
 	}
 	for _, part := range this.Partitions {
-		err = part.write(writer, this.Header.PartitionEntrySize)
+		err = part.writeByteOrder(writer, this.Header.PartitionEntrySize, order)
 		if err != nil {
 			return
 		}
@@ -335,6 +779,199 @@ func (this Table) Write(writer io.WriteSeeker) (err error) {
 	return
 }
 
+// WriteToDisk - write the table and its copy on other side of disk (see CreateOtherSideTable).
+// Nothing is written if any part of the tables is out of diskSizeBytes.
+func (this Table) WriteToDisk(writer io.WriteSeeker, diskSizeBytes uint64) error {
+	tables := []Table{this, this.CreateOtherSideTable()}
+	for _, table := range tables {
+		if err := table.checkWriteEnd(diskSizeBytes); err != nil {
+			return err
+		}
+	}
+	for _, table := range tables {
+		if err := table.Write(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinimalImage - disk image of diskSizeBytes, which contain protective MBR, the table and its copy on other side
+// of the disk only, other bytes are zeroes. The image is allocated in memory whole.
+func (this Table) MinimalImage(diskSizeBytes uint64) ([]byte, error) {
+	if diskSizeBytes < mbrSize || int64(diskSizeBytes) < 0 || uint64(int(diskSizeBytes)) != diskSizeBytes {
+		return nil, fmt.Errorf("%w: bad disk size %v", ErrWriteOutOfDisk, diskSizeBytes)
+	}
+	if err := this.checkWriteEnd(diskSizeBytes); err != nil {
+		return nil, err
+	}
+	if err := this.CreateOtherSideTable().checkWriteEnd(diskSizeBytes); err != nil {
+		return nil, err
+	}
+
+	image := &writeSeekBuffer{buf: make([]byte, diskSizeBytes)}
+	if err := this.WriteWithProtectiveMBR(image, diskSizeBytes); err != nil {
+		return nil, err
+	}
+	return image.buf, nil
+}
+
+// WriteWithProtectiveMBR - write protective MBR for the disk to LBA 0, then the table and its copy
+// on other side of the disk, as WriteToDisk. Nothing is written if the tables don't fit the disk.
+func (this Table) WriteWithProtectiveMBR(writer io.WriteSeeker, diskSizeBytes uint64) error {
+	for _, table := range []Table{this, this.CreateOtherSideTable()} {
+		if err := table.checkWriteEnd(diskSizeBytes); err != nil {
+			return err
+		}
+	}
+	if _, err := writer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := NewProtectiveMBR(diskSizeBytes / this.SectorSize).write(writer); err != nil {
+		return err
+	}
+	return this.WriteToDisk(writer, diskSizeBytes)
+}
+
+// RequiredBytes - minimal disk size for WriteToDisk: end of last region, which is written by Write of the table
+// or of its copy on other side of the disk. Usually it is end of backup header.
+func (this Table) RequiredBytes() uint64 {
+	var res uint64
+	for _, table := range []Table{this, this.CreateOtherSideTable()} {
+		for _, region := range table.writeRegions() {
+			start, ok := mul(int64(region.lba), int64(table.SectorSize))
+			if !ok || uint64(start)+region.size < uint64(start) {
+				return math.MaxUint64
+			}
+			if end := uint64(start) + region.size; end > res {
+				res = end
+			}
+		}
+	}
+	return res
+}
+
+// Place on disk, where Write of the table write data
+type writeRegion struct {
+	name string
+	lba  uint64
+	size uint64 // bytes
+}
+
+func (this Table) writeRegions() []writeRegion {
+	return []writeRegion{
+		{"header", this.Header.HeaderStartLBA, uint64(standardHeaderSize + len(this.Header.TrailingBytes))},
+		{"partitions array", this.Header.PartitionsTableStartLBA, uint64(len(this.Partitions)) * uint64(this.Header.PartitionEntrySize)},
+	}
+}
+
+// Check Write of the table doesn't write after diskSizeBytes
+func (this Table) checkWriteEnd(diskSizeBytes uint64) error {
+	for _, region := range this.writeRegions() {
+		start, ok := mul(int64(region.lba), int64(this.SectorSize))
+		if !ok || uint64(start) > diskSizeBytes || region.size > diskSizeBytes-uint64(start) {
+			return fmt.Errorf("%w: %v at LBA %v, disk size %v bytes", ErrWriteOutOfDisk, region.name, region.lba, diskSizeBytes)
+		}
+	}
+	return nil
+}
+
+// Header bytes as Write save it: with calculated partitions and header CRC
+func (this Table) serializedHeader() ([]byte, error) {
+	this.Header.PartitionsCRC = this.calcPartitionsCRC()
+	buf := &bytes.Buffer{}
+	err := this.Header.write(buf, true)
+	return buf.Bytes(), err
+}
+
+// HeaderSectorBytes - header sector as Write save it: header with calculated CRCs and TrailingBytes,
+// padded by zeroes to SectorSize.
+func (this Table) HeaderSectorBytes() ([]byte, error) {
+	header, err := this.serializedHeader()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(header)) > this.SectorSize {
+		return nil, fmt.Errorf("Header size with trailing bytes (%v) more then sector size (%v)", len(header), this.SectorSize)
+	}
+	res := make([]byte, this.SectorSize)
+	copy(res, header)
+	return res, nil
+}
+
+// WritePartitionEntry - write one partition entry to both partitions arrays and both headers with updated
+// partitions CRC: of the table and of its copy on other side of the disk.
+func (this Table) WritePartitionEntry(writer io.WriteSeeker, index int) (err error) {
+	if err = this.checkIndex(index); err != nil {
+		return err
+	}
+	this.Header.PartitionsCRC = this.calcPartitionsCRC()
+	for _, table := range []Table{this, this.CreateOtherSideTable()} {
+		if err = table.writeSidePartitionEntry(writer, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write partition entry and header of the table copy at Header.HeaderStartLBA
+func (this Table) writeSidePartitionEntry(writer io.WriteSeeker, index int) (err error) {
+	tablePos, tableOk := mul(int64(this.SectorSize), int64(this.Header.PartitionsTableStartLBA))
+	entryOffset, entryOk := mul(int64(index), int64(this.Header.PartitionEntrySize))
+	if !tableOk || !entryOk {
+		return fmt.Errorf("Seek overflow when write partition entry")
+	}
+	if _, err = writer.Seek(tablePos+entryOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if err = this.Partitions[index].write(writer, this.Header.PartitionEntrySize); err != nil {
+		return err
+	}
+
+	headerPos, ok := mul(int64(this.SectorSize), int64(this.Header.HeaderStartLBA))
+	if !ok {
+		return fmt.Errorf("Seek overflow when write header")
+	}
+	if _, err = writer.Seek(headerPos, io.SeekStart); err != nil {
+		return err
+	}
+	return this.Header.write(writer, true)
+}
+
+// Check the table and Write it if it is consistent.
+func (this Table) WriteValidated(writer io.WriteSeeker) error {
+	if err := this.checkWritable(); err != nil {
+		return err
+	}
+	return this.Write(writer)
+}
+
+// Checks, which have to be passed before write the table to disk
+func (this Table) checkWritable() error {
+	if uint32(len(this.Partitions)) != this.Header.PartitionsArrLen {
+		return fmt.Errorf("%w: %v partitions, PartitionsArrLen %v", ErrPartitionCountMismatch, len(this.Partitions), this.Header.PartitionsArrLen)
+	}
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && (p.FirstLBA < this.Header.FirstUsableLBA || p.LastLBA > this.Header.LastUsableLBA) {
+			return fmt.Errorf("%w: partition %v [%v-%v], usable [%v-%v]", ErrPartitionBeforeUsable, i, p.FirstLBA, p.LastLBA,
+				this.Header.FirstUsableLBA, this.Header.LastUsableLBA)
+		}
+	}
+	return nil
+}
+
+// Implement io.WriterTo. Write disk image from start of disk (LBA0) to end of the table.
+// LBA0 is filled by zeroes. Table serialized into memory buffer first, so it's intended for primary table only:
+// for backup table the buffer would be size of the whole disk.
+func (this Table) WriteTo(writer io.Writer) (n int64, err error) {
+	buf := &writeSeekBuffer{}
+	err = this.Write(buf)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.NewReader(buf.buf).WriteTo(writer)
+}
+
 // Use for create guid predefined values in snippet http://play.golang.org/p/uOd_WQtiwE
 func StringToGuid(guid string) (res [16]byte, err error) {
 	byteOrder := [...]int{3, 2, 1, 0, -1, 5, 4, -1, 7, 6, -1, 8, 9, -1, 10, 11, 12, 13, 14, 15}
@@ -433,7 +1070,7 @@ func NewTable(diskSize uint64, args *NewTableArgs) Table {
 	}
 
 	ptStartLBA := uint64(2)
-	numParts := 128
+	numParts := standardPartitionsArrLen
 	partitionsTableSize := uint64(standardPartitionEntrySize) * uint64(numParts)
 	partitionSizeInSector := partitionsTableSize / uint64(args.SectorSize)
 	if partitionsTableSize%uint64(args.SectorSize) != 0 {
@@ -483,6 +1120,41 @@ func mul(a, b int64) (res int64, ok bool) {
 	return c, c/b == a
 }
 
+// In-memory io.WriteSeeker. Buffer grows when write after the end.
+type writeSeekBuffer struct {
+	buf    []byte
+	offset int64
+}
+
+func (this *writeSeekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += this.offset
+	case io.SeekEnd:
+		offset += int64(len(this.buf))
+	default:
+		return this.offset, fmt.Errorf("Bad whence: %v", whence)
+	}
+	if offset < 0 {
+		return this.offset, fmt.Errorf("Negative seek position: %v", offset)
+	}
+	this.offset = offset
+	return offset, nil
+}
+
+func (this *writeSeekBuffer) Write(p []byte) (n int, err error) {
+	needLen := this.offset + int64(len(p))
+	if needLen > int64(len(this.buf)) {
+		newBuf := make([]byte, needLen)
+		copy(newBuf, this.buf)
+		this.buf = newBuf
+	}
+	copy(this.buf[this.offset:], p)
+	this.offset += int64(len(p))
+	return len(p), nil
+}
+
 func guidToString(byteGuid [16]byte) string {
 	byteToChars := func(b byte) (res []byte) {
 		res = make([]byte, 0, 2)
@@ -537,14 +1209,48 @@ func guidToString(byteGuid [16]byte) string {
 }
 
 func NewGUID() Guid {
-	var res Guid
-	_, err := rand.Read(res[:])
+	res, err := newGUID()
 	if err != nil {
 		panic(err)
 	}
+	return res
+}
 
-	// set predefined bits for UUIDv4
-	res[6] = (res[6] & 0x0f) | 0x40 // Version 4
+func newGUID() (Guid, error) {
+	var res Guid
+	if _, err := rand.Read(res[:]); err != nil {
+		return res, err
+	}
+	return guidV4(res), nil
+}
+
+// Set predefined bits for UUIDv4 to random bytes
+func guidV4(res Guid) Guid {
+	// third group is stored in little-endian
+	res[7] = (res[7] & 0x0f) | 0x40 // Version 4
 	res[8] = (res[8] & 0x3f) | 0x80 // Variant 10
 	return res
 }
+
+// DeterministicGUIDSource - generator of version 4 guids, which are same for same seed. It isn't safe for concurrent use.
+// Use it for NewTableArgs.DiskGuid and AddPartitionWithID for reproducible tables in tests.
+func DeterministicGUIDSource(seed int64) func() Guid {
+	random := mathrand.New(mathrand.NewSource(seed))
+	return func() Guid {
+		var res Guid
+		random.Read(res[:])
+		return guidV4(res)
+	}
+}
+
+// NewDiskGUID - set random DiskGUID and recalculate header CRC. Partitions and their GUIDs aren't changed,
+// so it is enough for attach restored copy of the disk alongside the original.
+func (this *Table) NewDiskGUID() error {
+	guid, err := newGUID()
+	if err != nil {
+		return err
+	}
+	this.Header.DiskGUID = guid
+	this.Touch()
+	return nil
+}
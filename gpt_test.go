@@ -2,6 +2,12 @@ package gpt
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +48,26 @@ func (this *randomWriteBuffer) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+const testDiskSize = 1953525168 * 512 // Size of the disk in GPT_TEST_HEADER, bytes
+
+// Disk image start with primary table from GPT_TEST_HEADER and GPT_TEST_ENTRIES
+func testDiskBuf() []byte {
+	buf := make([]byte, 512+512+32*512)
+	copy(buf[512:], GPT_TEST_HEADER)
+	copy(buf[1024:], GPT_TEST_ENTRIES)
+	return buf
+}
+
+func readTestTable(t *testing.T) Table {
+	reader := bytes.NewReader(testDiskBuf())
+	reader.Seek(512, 0)
+	table, err := ReadTable(reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
 func TestHeaderRead(t *testing.T) {
 	reader := bytes.NewReader(GPT_TEST_HEADER)
 	h, err := readHeader(reader, 512)
@@ -109,6 +135,100 @@ func TestHeaderReadWrite(t *testing.T) {
 	}
 }
 
+func TestHeadersEqual(t *testing.T) {
+	h, err := readHeader(bytes.NewReader(GPT_TEST_HEADER), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2 := h
+	h2.TrailingBytes = append([]byte(nil), h.TrailingBytes...)
+	if !HeadersEqual(h, h2) {
+		t.Error("Copy isn't equal")
+	}
+
+	h2.TrailingBytes[100] = 1
+	if HeadersEqual(h, h2) {
+		t.Error("Trailing bytes")
+	}
+	h2.TrailingBytes = h2.TrailingBytes[:100]
+	if HeadersEqual(h, h2) {
+		t.Error("Trailing bytes len")
+	}
+
+	h2 = h
+	h2.Reserved = 1
+	if HeadersEqual(h, h2) {
+		t.Error("Reserved")
+	}
+}
+
+func TestAreClones(t *testing.T) {
+	table := readTestTable(t)
+	clone := table.CreateOtherSideTable()
+	clone.Partitions = clone.Partitions[:3]
+	if !AreClones(table, clone) || !AreClones(clone, table) {
+		t.Error("Exact clone")
+	}
+
+	randomized := table.copy()
+	randomized.Partitions[1].Id = NewGUID()
+	if AreClones(table, randomized) {
+		t.Error("Randomized partition guid")
+	}
+	randomized = table.copy()
+	randomized.Header.DiskGUID = NewGUID()
+	if AreClones(table, randomized) {
+		t.Error("Randomized disk guid")
+	}
+	moved := table.copy()
+	moved.Partitions[3] = moved.Partitions[2]
+	moved.Partitions[2] = Partition{}
+	if AreClones(table, moved) {
+		t.Error("Moved partition")
+	}
+}
+
+func TestBackupHeader(t *testing.T) {
+	h, err := readHeader(bytes.NewReader(GPT_TEST_HEADER), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := h.BackupHeader(testDiskSize, 512, h.partitionsTableSectors(512))
+	if backup.HeaderStartLBA != h.HeaderCopyStartLBA || backup.HeaderCopyStartLBA != h.HeaderStartLBA {
+		t.Error("Header LBAs aren't swapped: ", backup.HeaderStartLBA, backup.HeaderCopyStartLBA)
+	}
+	if backup.PartitionsTableStartLBA != h.LastUsableLBA+1 {
+		t.Error("Partitions table start: ", backup.PartitionsTableStartLBA)
+	}
+	if backup.CRC != backup.calcCRC() {
+		t.Error("CRC")
+	}
+	if backup.FirstUsableLBA != h.FirstUsableLBA || backup.LastUsableLBA != h.LastUsableLBA || backup.PartitionsCRC != h.PartitionsCRC {
+		t.Error("Changed fields")
+	}
+	backup.TrailingBytes[0] = 1
+	if h.TrailingBytes[0] != 0 {
+		t.Error("Shared trailing bytes")
+	}
+
+	otherSide := readTestTable(t).CreateOtherSideTable().Header
+	if !HeadersEqual(otherSide, h.BackupHeader(testDiskSize, 512, 32)) {
+		t.Error("Differ from CreateOtherSideTable")
+	}
+	// Backup array is right before backup header, independent of LastUsableLBA
+	table := readTestTable(t)
+	table.Header.LastUsableLBA -= 100
+	table.Touch()
+	otherSide = table.CreateOtherSideTable().Header
+	if otherSide.PartitionsTableStartLBA != h.HeaderCopyStartLBA-32 ||
+		!HeadersEqual(otherSide, table.Header.BackupHeader(testDiskSize, 512, 32)) {
+		t.Error("Shrunk usable space: ", otherSide.PartitionsTableStartLBA)
+	}
+	if primary := table.CreateOtherSideTable().CreateOtherSideTable(); !HeadersEqual(primary.Header, table.Header) {
+		t.Error("Primary from backup")
+	}
+}
+
 func TestEntryReadWrite(t *testing.T) {
 	testEntry := make([]byte, 137)
 	copy(testEntry, GPT_TEST_ENTRIES[0:128])
@@ -164,6 +284,150 @@ func TestPartitionRead(t *testing.T) {
 	}
 }
 
+func TestReadPartitionArray(t *testing.T) {
+	buf := make([]byte, 512*40+len(GPT_TEST_ENTRIES))
+	copy(buf[512*2:], GPT_TEST_ENTRIES)
+	copy(buf[512*40:], GPT_TEST_ENTRIES)
+	reader := bytes.NewReader(buf)
+
+	parts2, err := readPartitionArray(reader, 512, 2, 128, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts40, err := readPartitionArray(reader, 512, 40, 128, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts2) != 128 || len(parts40) != 128 {
+		t.Fatal(len(parts2), len(parts40))
+	}
+	for i := range parts2 {
+		if parts2[i].Id != parts40[i].Id || parts2[i].FirstLBA != parts40[i].FirstLBA {
+			t.Error("Different partitions: ", i)
+		}
+	}
+	if parts2[0].FirstLBA != 2048 {
+		t.Error("First LBA: ", parts2[0].FirstLBA)
+	}
+
+	if _, err = readPartitionArray(reader, 512, 41, 128, 128); err == nil {
+		t.Error("Read after end")
+	}
+	if _, err = readPartitionArray(reader, 512, 2, 128, 100); !errors.Is(err, ErrBadEntrySize) {
+		t.Error("Small entry size: ", err)
+	}
+}
+
+func TestReadTruncatedTable(t *testing.T) {
+	buf := testDiskBuf()[:1024+10*128+50]
+	reader := bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	table, err := ReadTable(reader, 512)
+	if !errors.Is(err, ErrTruncatedArray) {
+		t.Fatal(err)
+	}
+	if len(table.Partitions) != 10 {
+		t.Fatal(len(table.Partitions))
+	}
+	if table.Partitions[2].Name() != "primary" {
+		t.Error("Bad partition")
+	}
+
+	// Truncated at entry boundary
+	buf = testDiskBuf()[:1024+10*128]
+	reader = bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	table, err = ReadTable(reader, 512)
+	if !errors.Is(err, ErrTruncatedArray) || len(table.Partitions) != 10 {
+		t.Error(err, len(table.Partitions))
+	}
+
+	// Huge PartitionsArrLen with valid header CRC
+	header := readTestTable(t).Header
+	header.PartitionsArrLen = 0xFFFFFFFF
+	headerBuf := &bytes.Buffer{}
+	header.write(headerBuf, true)
+	buf = testDiskBuf()
+	copy(buf[512:], headerBuf.Bytes())
+	reader = bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	table, err = ReadTable(reader, 512)
+	if !errors.Is(err, ErrTruncatedArray) || len(table.Partitions) != 128 {
+		t.Error(err, len(table.Partitions))
+	}
+}
+
+func TestReadErrorOffset(t *testing.T) {
+	buf := testDiskBuf()
+	reader := bytes.NewReader(buf[:512+44]) // Middle of FirstUsableLBA
+	reader.Seek(512, io.SeekStart)
+	_, err := ReadTable(reader, 512)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || err.Error() != "read header field FirstUsableLBA at offset 40: unexpected EOF" {
+		t.Error(err)
+	}
+
+	reader = bytes.NewReader(buf[:512+100])
+	reader.Seek(512, io.SeekStart)
+	if _, err = ReadTable(reader, 512); !errors.Is(err, io.ErrUnexpectedEOF) || !strings.Contains(err.Error(), "offset 92") {
+		t.Error(err)
+	}
+
+	_, err = readPartition(bytes.NewReader(buf[1024:1024+60]), 128)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || err.Error() != "read partition entry field PartNameUTF16 at offset 56: unexpected EOF" {
+		t.Error(err)
+	}
+}
+
+func TestPartitionName(t *testing.T) {
+	var p Partition
+	if p.MaxNameRunes() != 36 {
+		t.Error(p.MaxNameRunes())
+	}
+
+	ascii := strings.Repeat("a", 36)
+	if !p.NameFits(ascii) {
+		t.Error("36 ascii chars")
+	}
+	if err := p.SetName(ascii); err != nil || p.Name() != ascii {
+		t.Error(err, p.Name())
+	}
+
+	emoji := strings.Repeat("\U0001F600", 36)
+	if p.NameFits(emoji) {
+		t.Error("36 emoji")
+	}
+	if err := p.SetName(emoji); !errors.Is(err, ErrNameTooLong) {
+		t.Error(err)
+	}
+	if p.Name() != ascii {
+		t.Error("Name changed on error")
+	}
+
+	if err := p.SetName("Имя \U0001F600"); err != nil || p.Name() != "Имя \U0001F600" {
+		t.Error(err, p.Name())
+	}
+}
+
+func TestPartitionHasNonZeroReserved(t *testing.T) {
+	p := Partition{TrailingBytes: make([]byte, 128)}
+	p.SetName(strings.Repeat("a", 36))
+	if p.HasNonZeroReserved() {
+		t.Error("Full name")
+	}
+
+	p.SetName("name")
+	p.PartNameUTF16[10] = 1
+	if !p.HasNonZeroReserved() {
+		t.Error("Byte after name terminator")
+	}
+
+	p.SetName("name")
+	p.TrailingBytes[127] = 1
+	if !p.HasNonZeroReserved() {
+		t.Error("Trailing byte")
+	}
+}
+
 func TestPartitionBadWrite(t *testing.T) {
 	var p Partition
 	p.TrailingBytes = []byte{1, 2, 3}
@@ -194,6 +458,407 @@ func TestReadWriteTable(t *testing.T) {
 	}
 }
 
+func TestHeaderSectorBytes(t *testing.T) {
+	table := readTestTable(t)
+	sector, err := table.HeaderSectorBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sector) != 512 || !bytes.Equal(sector, GPT_TEST_HEADER) {
+		t.Error("Bad sector")
+	}
+	if _, err = readHeader(bytes.NewReader(sector), 512); err != nil {
+		t.Error(err)
+	}
+
+	table.Header.TrailingBytes = table.Header.TrailingBytes[:10]
+	sector, err = table.HeaderSectorBytes()
+	if err != nil || len(sector) != 512 {
+		t.Error(len(sector), err)
+	}
+	if h, err := readHeader(bytes.NewReader(sector), 512); err != nil || h.DiskGUID != table.Header.DiskGUID {
+		t.Error("Reparse short trailing bytes: ", err)
+	}
+
+	table.Header.TrailingBytes = make([]byte, 1000)
+	if _, err = table.HeaderSectorBytes(); err == nil {
+		t.Error("Header more then sector")
+	}
+}
+
+func TestWritePartitionEntry(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	table := NewTable(diskSize, nil)
+	for _, name := range []string{"first", "second"} {
+		if _, err := table.AddPartition(GUID_LINUX_FS, 1024*1024, 0, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disk := &randomWriteBuffer{}
+	if err := table.WriteToDisk(disk, diskSize); err != nil {
+		t.Fatal(err)
+	}
+
+	table.Partitions[1].Flags[0] = 1
+	expected := &randomWriteBuffer{}
+	if err := table.WriteToDisk(expected, diskSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := table.WritePartitionEntry(disk, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(disk.buf) != diskSize || !bytes.Equal(disk.buf, expected.buf) {
+		t.Error("Differ from full write")
+	}
+	if backup, err := ReadBackupTable(bytes.NewReader(disk.buf), 512); err != nil || backup.Partitions[1].Flags[0] != 1 {
+		t.Error("Backup: ", err)
+	}
+
+	if err := table.WritePartitionEntry(disk, 128); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestWriteValidated(t *testing.T) {
+	table := readTestTable(t)
+	buf := &randomWriteBuffer{}
+	if err := table.WriteValidated(buf); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buf.buf, testDiskBuf()) {
+		t.Error("Bad write")
+	}
+
+	table.Partitions = table.Partitions[:10]
+	buf = &randomWriteBuffer{}
+	if err := table.WriteValidated(buf); !errors.Is(err, ErrPartitionCountMismatch) {
+		t.Error(err)
+	}
+	if len(buf.buf) != 0 {
+		t.Error("Write after error")
+	}
+}
+
+func TestWriteValidatedOutOfUsable(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[0].FirstLBA = table.Header.FirstUsableLBA - 1
+	table.Touch()
+	buf := &randomWriteBuffer{}
+	if err := table.WriteValidated(buf); !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(err)
+	}
+	if err := table.Validate(); !errors.Is(err, ErrPartitionBeforeUsable) || !strings.Contains(err.Error(), "partition 0") {
+		t.Error(err)
+	}
+
+	table = readTestTable(t)
+	table.Partitions[2].LastLBA = table.Header.LastUsableLBA + 1
+	table.Touch()
+	if err := table.WriteValidated(buf); !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(err)
+	}
+	if err := table.Validate(); !errors.Is(err, ErrPartitionBeforeUsable) || !strings.Contains(err.Error(), "partition 2") {
+		t.Error(err)
+	}
+	if len(buf.buf) != 0 {
+		t.Error("Write after error")
+	}
+}
+
+func TestWriteToDisk(t *testing.T) {
+	const diskSize = 1024 * 1024
+	table := NewTable(diskSize, nil)
+	table.Partitions[0] = Partition{Type: GUID_LVM, FirstLBA: 34, LastLBA: 1000}
+
+	disk := &randomWriteBuffer{buf: make([]byte, diskSize)}
+	if err := table.WriteToDisk(disk, diskSize); err != nil {
+		t.Fatal(err)
+	}
+	if len(disk.buf) != diskSize {
+		t.Error("Disk size changed: ", len(disk.buf))
+	}
+	reader := bytes.NewReader(disk.buf)
+	reader.Seek(512, 0)
+	primary, err := ReadTable(reader, 512)
+	if err != nil || primary.Partitions[0].LastLBA != 1000 {
+		t.Error("Primary: ", err)
+	}
+	reader.Seek(diskSize-512, 0)
+	backup, err := ReadTable(reader, 512)
+	if err != nil || !backup.IsBackupHeader() || backup.Partitions[0].LastLBA != 1000 {
+		t.Error("Backup: ", err)
+	}
+
+	// Backup header is after end of the disk
+	disk = &randomWriteBuffer{buf: make([]byte, diskSize/2)}
+	if err := table.WriteToDisk(disk, diskSize/2); !errors.Is(err, ErrWriteOutOfDisk) {
+		t.Error(err)
+	}
+	if !bytes.Equal(disk.buf, make([]byte, diskSize/2)) {
+		t.Error("Write on error")
+	}
+}
+
+func TestRequiredBytes(t *testing.T) {
+	table := readTestTable(t)
+	if res := table.RequiredBytes(); res != testDiskSize {
+		t.Error(res)
+	}
+	if res := table.CreateOtherSideTable().RequiredBytes(); res != testDiskSize {
+		t.Error("Backup: ", res)
+	}
+
+	const diskSize = 1024 * 1024
+	table = NewTable(diskSize, nil)
+	if err := table.WriteToDisk(&randomWriteBuffer{}, table.RequiredBytes()); err != nil {
+		t.Error(err)
+	}
+	if err := table.WriteToDisk(&randomWriteBuffer{}, table.RequiredBytes()-1); !errors.Is(err, ErrWriteOutOfDisk) {
+		t.Error(err)
+	}
+}
+
+// io.Reader, which can be copied by its WriteTo only
+type writerToReader struct {
+	io.WriterTo
+}
+
+func (this writerToReader) Read(p []byte) (int, error) {
+	return 0, errors.New("Read is called instead of WriteTo")
+}
+
+func TestTableWriteTo(t *testing.T) {
+	table := readTestTable(t)
+
+	buf := &bytes.Buffer{}
+	n, err := io.Copy(buf, writerToReader{table})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(testDiskBuf())) || !bytes.Equal(buf.Bytes(), testDiskBuf()) {
+		t.Error("Bad written image: ", n)
+	}
+
+	reader := bytes.NewReader(buf.Bytes())
+	reader.Seek(512, 0)
+	table2, err := ReadTable(reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table2.Header.CRC != table.Header.CRC || table2.Header.PartitionsCRC != table.Header.PartitionsCRC {
+		t.Error("Different tables")
+	}
+}
+
+func TestPartitionArrayBytes(t *testing.T) {
+	table := readTestTable(t)
+	arr, err := table.PartitionArrayBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(arr, GPT_TEST_ENTRIES) {
+		t.Error("Bad array bytes")
+	}
+	if crc32.ChecksumIEEE(arr) != table.calcPartitionsCRC() {
+		t.Error("CRC")
+	}
+
+	table.Partitions = table.Partitions[:3]
+	arr, err = table.PartitionArrayBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(arr, GPT_TEST_ENTRIES) {
+		t.Error("Bad padded array bytes")
+	}
+
+	table.Partitions = make([]Partition, 129)
+	if _, err = table.PartitionArrayBytes(); !errors.Is(err, ErrPartitionCountMismatch) {
+		t.Error(err)
+	}
+}
+
+func TestReadTableSkipPartitionCRC(t *testing.T) {
+	buf := testDiskBuf()
+	buf[1024+32]++ // FirstLBA of first partition
+	reader := bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	if _, err := ReadTable(reader, 512); !errors.Is(err, ErrBadPartitionsCRC) {
+		t.Error(err)
+	}
+
+	reader.Seek(512, 0)
+	table, err := ReadTableWithOptions(reader, 512, &ReadOptions{SkipPartitionCRC: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Partitions) != 128 || table.Partitions[0].FirstLBA != 2049 || table.Partitions[2].Name() != "primary" {
+		t.Error("Bad partitions")
+	}
+}
+
+func TestReadTableRejectUnknownRevision(t *testing.T) {
+	table := readTestTable(t)
+	table.Header.Revision = 0x00020000
+	disk := &randomWriteBuffer{buf: testDiskBuf()}
+	if err := table.Write(disk); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bytes.NewReader(disk.buf)
+	reader.Seek(512, 0)
+	_, err := ReadTableWithOptions(reader, 512, &ReadOptions{RejectUnknownRevision: true})
+	if !errors.Is(err, ErrUnsupportedRevision) {
+		t.Error(err)
+	}
+
+	reader.Seek(512, 0)
+	res, err := ReadTable(reader, 512)
+	if err != nil || res.Header.Revision != 0x00020000 {
+		t.Error(err)
+	}
+}
+
+func TestByteOrder(t *testing.T) {
+	table := readTestTable(t)
+	buf := &bytes.Buffer{}
+	header := table.Header
+	if err := header.writeByteOrder(buf, true, binary.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes()[8:12], []byte{0, 1, 0, 0}) {
+		t.Error("Revision isn't big endian: ", buf.Bytes()[8:12])
+	}
+	readed, err := readHeaderByteOrder(bytes.NewReader(buf.Bytes()), 512, binary.BigEndian)
+	if err != nil || !HeadersEqual(readed, table.Header) {
+		t.Error(err)
+	}
+
+	disk := &randomWriteBuffer{}
+	if err = table.WriteByteOrder(disk, binary.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	reader := bytes.NewReader(disk.buf)
+	reader.Seek(512, 0)
+	bigEndian, err := ReadTableWithOptions(reader, 512, &ReadOptions{ByteOrder: binary.BigEndian})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HeadersEqual(bigEndian.Header, table.Header) || !reflect.DeepEqual(bigEndian.Partitions, table.Partitions) {
+		t.Error("Big endian table differs")
+	}
+
+	reader.Seek(512, 0)
+	if _, err = ReadTable(reader, 512); err == nil {
+		t.Error("Big endian table read as little endian")
+	}
+}
+
+func TestReadTableUsedEntriesCRCFallback(t *testing.T) {
+	table := readTestTable(t)
+	table.Header.PartitionsCRC = table.calcUsedPartitionsCRC()
+	disk := &randomWriteBuffer{buf: testDiskBuf()}
+	if _, err := disk.Seek(512, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Header.write(disk, true); err != nil { // Table.Write save CRC of full array
+		t.Fatal(err)
+	}
+
+	reader := bytes.NewReader(disk.buf)
+	reader.Seek(512, 0)
+	if _, err := ReadTable(reader, 512); !errors.Is(err, ErrBadPartitionsCRC) {
+		t.Error(err)
+	}
+
+	reader.Seek(512, 0)
+	_, res, err := ReadTableVerbose(reader, 512, &ReadOptions{UsedEntriesCRCFallback: true})
+	if err != nil || !res.HeaderCRCValid || !res.PartitionsCRCValid || !res.PartitionsCRCUsedEntries {
+		t.Error(res, err)
+	}
+
+	reader = bytes.NewReader(testDiskBuf())
+	reader.Seek(512, 0)
+	_, res, err = ReadTableVerbose(reader, 512, &ReadOptions{UsedEntriesCRCFallback: true})
+	if err != nil || !res.PartitionsCRCValid || res.PartitionsCRCUsedEntries {
+		t.Error("Full array: ", res, err)
+	}
+}
+
+func TestReadTableVerbose(t *testing.T) {
+	reader := bytes.NewReader(testDiskBuf())
+	reader.Seek(512, 0)
+	table, res, err := ReadTableVerbose(reader, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.HeaderCRCValid || !res.PartitionsCRCValid || res.Source != SourcePrimary {
+		t.Error(res)
+	}
+	if len(table.Partitions) != 128 {
+		t.Error(len(table.Partitions))
+	}
+
+	buf := testDiskBuf()
+	buf[512+8]++   // Revision
+	buf[1024+32]++ // FirstLBA of first partition
+	reader = bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	_, res, err = ReadTableVerbose(reader, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HeaderCRCValid || res.PartitionsCRCValid {
+		t.Error(res)
+	}
+
+	// Bad header CRC with garbage partitions array size: array isn't read
+	buf = testDiskBuf()
+	binary.LittleEndian.PutUint32(buf[512+80:], 0xFFFFFFFF) // PartitionsArrLen
+	reader = bytes.NewReader(buf)
+	reader.Seek(512, 0)
+	table, res, err = ReadTableVerbose(reader, 512, nil)
+	if err != nil || res.HeaderCRCValid || table.Partitions != nil {
+		t.Error(res, err, len(table.Partitions))
+	}
+	reader.Seek(512, 0)
+	if _, err = ReadTable(reader, 512); err != ErrBadHeaderCRC {
+		t.Error(err)
+	}
+
+	backup := readTestTable(t).CreateOtherSideTable()
+	backup.Header.HeaderStartLBA = 10 // for small buffer
+	backup.Header.PartitionsTableStartLBA = 11
+	backup.Touch()
+	disk := &randomWriteBuffer{}
+	backup.Write(disk)
+	reader = bytes.NewReader(disk.buf)
+	reader.Seek(10*512, 0)
+	_, res, err = ReadTableVerbose(reader, 512, nil)
+	if err != nil || !res.HeaderCRCValid || !res.PartitionsCRCValid || res.Source != SourceBackup {
+		t.Error(res, err)
+	}
+}
+
+func benchmarkReadTable(b *testing.B, options *ReadOptions) {
+	reader := bytes.NewReader(testDiskBuf())
+	for i := 0; i < b.N; i++ {
+		reader.Seek(512, 0)
+		if _, err := ReadTableWithOptions(reader, 512, options); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadTable(b *testing.B) {
+	benchmarkReadTable(b, nil)
+}
+
+func BenchmarkReadTableSkipPartitionCRC(b *testing.B) {
+	benchmarkReadTable(b, &ReadOptions{SkipPartitionCRC: true})
+}
+
 func TestNewTable(t *testing.T) {
 	guid := Guid{0xc5, 0x7f, 0x7e, 0x46, 0x36, 0x2b, 0x4e, 0x60, 0x9a, 0xa9, 0xa6, 0xe9, 0xdd, 0x85, 0x94, 0xa6}
 	ssize := 4096
@@ -390,6 +1055,18 @@ func TestTableCreateOtherSide(t *testing.T) {
 	}
 }
 
+func TestHeaderLBAs(t *testing.T) {
+	table := readTestTable(t)
+	if table.IsBackupHeader() || table.PrimaryHeaderLBA() != 1 || table.BackupHeaderLBA() != 1953525167 {
+		t.Error(table.IsBackupHeader(), table.PrimaryHeaderLBA(), table.BackupHeaderLBA())
+	}
+
+	backup := table.CreateOtherSideTable()
+	if !backup.IsBackupHeader() || backup.PrimaryHeaderLBA() != 1 || backup.BackupHeaderLBA() != 1953525167 {
+		t.Error(backup.IsBackupHeader(), backup.PrimaryHeaderLBA(), backup.BackupHeaderLBA())
+	}
+}
+
 func TestTableCopy(t *testing.T) {
 	buf := make([]byte, 512+512+32*512)
 	copy(buf[512:], GPT_TEST_HEADER)
@@ -601,6 +1278,70 @@ func TestTableNewSize(t *testing.T) {
 	}
 }
 
+func TestGuidBytes(t *testing.T) {
+	raw := [16]byte{40, 115, 42, 193, 31, 248, 210, 17, 186, 75, 0, 160, 201, 62, 201, 59}
+	g := GuidFromBytes(raw)
+	if g.String() != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Error(g.String())
+	}
+	if g.Bytes() != raw {
+		t.Error(g.Bytes())
+	}
+	if !bytes.Equal(g[:], raw[:]) {
+		t.Error("Slice: ", g[:])
+	}
+
+	var h Header
+	copy(h.DiskGUID[:], raw[:])
+	if h.DiskGUID != g {
+		t.Error("Copy to slice: ", h.DiskGUID)
+	}
+}
+
+func TestGuidEndian(t *testing.T) {
+	raw := [16]byte{0xC1, 0x2A, 0x73, 0x28, 0xF8, 0x1F, 0x11, 0xD2, 0xBA, 0x4B, 0x00, 0xA0, 0xC9, 0x3E, 0xC9, 0x3B}
+
+	mixed := ParseGUIDRaw(raw, true)
+	if mixed.String() != "28732AC1-1FF8-D211-BA4B-00A0C93EC93B" || FormatGUID(mixed, true) != mixed.String() {
+		t.Error("Mixed: ", mixed.String())
+	}
+	if FormatGUID(mixed, false) != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Error("Mixed as big endian: ", FormatGUID(mixed, false))
+	}
+
+	bigEndian := ParseGUIDRaw(raw, false)
+	if bigEndian.String() != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Error("Big endian: ", bigEndian.String())
+	}
+	if bigEndian != [16]byte{40, 115, 42, 193, 31, 248, 210, 17, 186, 75, 0, 160, 201, 62, 201, 59} {
+		t.Error("Big endian bytes: ", bigEndian)
+	}
+}
+
+func TestGuidVersion(t *testing.T) {
+	raw, err := StringToGuid("DC2F50B0-98DE-4681-A868-42E9FEBD6E3E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guid := Guid(raw)
+	if guid.Version() != 4 || guid.Variant() != "RFC4122" {
+		t.Error(guid.Version(), guid.Variant())
+	}
+
+	// Time based guid of ESP type
+	esp := Guid(GUID_EFI_SYSTEM)
+	if esp.Version() != 1 || esp.Variant() != "RFC4122" {
+		t.Error(esp.Version(), esp.Variant())
+	}
+
+	for i := 0; i < 10; i++ {
+		guid = NewGUID()
+		if guid.Version() != 4 || guid.Variant() != "RFC4122" || guid.String()[14] != '4' {
+			t.Error(guid)
+		}
+	}
+}
+
 func TestGuidToString(t *testing.T) {
 	guid := [...]byte{40, 115, 42, 193, 31, 248, 210, 17, 186, 75, 0, 160, 201, 62, 201, 59}
 	guidS := guidToString(guid)
@@ -630,3 +1371,164 @@ func TestStringToGuid(t *testing.T) {
 		t.Error("Must return error")
 	}
 }
+
+func TestPartitionEntryBytes(t *testing.T) {
+	table := readTestTable(t)
+	array, err := table.PartitionArrayBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []byte
+	for i := range table.Partitions {
+		entry, err := table.PartitionEntryBytes(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := i * int(table.Header.PartitionEntrySize)
+		if !bytes.Equal(entry, array[start:start+int(table.Header.PartitionEntrySize)]) {
+			t.Error("Entry mismatch: ", i)
+		}
+		entries = append(entries, entry...)
+	}
+	if crc32.ChecksumIEEE(entries) != table.Header.PartitionsCRC {
+		t.Error("Bad CRC")
+	}
+
+	if _, err = table.PartitionEntryBytes(len(table.Partitions)); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+}
+
+func TestPartitionsByName(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].SetName("root")
+	table.Partitions[5].SetName("empty")
+	byName := table.PartitionsByName()
+	if index, ok := byName["primary"]; !ok || index != 2 {
+		t.Error(index, ok)
+	}
+	if byName["root"] != 1 {
+		t.Error(byName)
+	}
+	if _, ok := byName["empty"]; ok {
+		t.Error("Empty partition")
+	}
+
+	table.Partitions[0].SetName("primary")
+	if byName = table.PartitionsByName(); byName["primary"] != 2 {
+		t.Error("Last wins: ", byName)
+	}
+}
+
+func TestNewDiskGUID(t *testing.T) {
+	table := readTestTable(t)
+	old := table.copy()
+	if err := table.NewDiskGUID(); err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.DiskGUID == old.Header.DiskGUID || table.Header.DiskGUID.Version() != 4 {
+		t.Error(table.Header.DiskGUID)
+	}
+	if !reflect.DeepEqual(table.Partitions, old.Partitions) || table.Header.PartitionsCRC != old.Header.PartitionsCRC {
+		t.Error("Partitions changed")
+	}
+	if table.Header.CRC != table.Header.calcCRC() {
+		t.Error("Bad header CRC")
+	}
+
+	table.Header.DiskGUID = old.Header.DiskGUID
+	table.Header.CRC = old.Header.CRC
+	if !reflect.DeepEqual(table, old) {
+		t.Error("Not only DiskGUID changed")
+	}
+}
+
+func TestHasStandardLayout(t *testing.T) {
+	table := readTestTable(t)
+	if !table.HasStandardLayout() {
+		t.Error("Fixture")
+	}
+	if table.CreateOtherSideTable().HasStandardLayout() {
+		t.Error("Backup table")
+	}
+	table.Header.PartitionsTableStartLBA = 3
+	if table.HasStandardLayout() {
+		t.Error("Array at LBA 3")
+	}
+
+	table = NewTable(1024*1024*10, nil)
+	if !table.HasStandardLayout() {
+		t.Error("New table")
+	}
+	if err := table.SetEntrySize(256); err != nil {
+		t.Fatal(err)
+	}
+	if table.HasStandardLayout() {
+		t.Error("Entry size 256")
+	}
+}
+
+func TestMinimalImage(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	table := NewTable(diskSize, nil)
+	if _, err := table.AddPartition(GUID_LINUX_FS, 1024*1024, 0, "data"); err != nil {
+		t.Fatal(err)
+	}
+	image, err := table.MinimalImage(diskSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(image) != diskSize {
+		t.Fatal(len(image))
+	}
+	if err = table.RequireProtectiveMBR(bytes.NewReader(image)); err != nil {
+		t.Error(err)
+	}
+
+	reader := bytes.NewReader(image)
+	reader.Seek(512, io.SeekStart)
+	primary, err := ReadTable(reader, 512)
+	if err != nil || !reflect.DeepEqual(primary, table) {
+		t.Error("Primary: ", err)
+	}
+	backup, err := ReadBackupTable(reader, 512)
+	if err != nil || !backup.IsBackupHeader() || !AreClones(primary, backup) {
+		t.Error("Backup: ", err)
+	}
+
+	if _, err = table.MinimalImage(diskSize - 512); !errors.Is(err, ErrWriteOutOfDisk) {
+		t.Error(err)
+	}
+}
+
+func TestDeterministicGUIDSource(t *testing.T) {
+	build := func(seed int64) []byte {
+		source := DeterministicGUIDSource(seed)
+		table := NewTable(1024*1024*10, &NewTableArgs{DiskGuid: source()})
+		if _, err := table.AddPartitionWithID(GUID_EFI_SYSTEM, 1024*1024, 0, "esp", source()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := table.AddPartitionWithID(GUID_LINUX_FS, 1024*1024, 0, "root", source()); err != nil {
+			t.Fatal(err)
+		}
+		if table.Header.DiskGUID.Version() != 4 || table.Partitions[0].Id.Version() != 4 || table.Partitions[0].Id.Variant() != "RFC4122" {
+			t.Error("Not version 4 guid")
+		}
+		if table.Partitions[0].Id == table.Partitions[1].Id || table.Partitions[0].Id == table.Header.DiskGUID {
+			t.Error("Same guids")
+		}
+		image, err := table.MinimalImage(1024 * 1024 * 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return image
+	}
+
+	if !bytes.Equal(build(1), build(1)) {
+		t.Error("Same seed")
+	}
+	if bytes.Equal(build(1), build(2)) {
+		t.Error("Other seed")
+	}
+}
@@ -0,0 +1,128 @@
+package gpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const mbrSize = 512                // MBR always take first 512 bytes of disk, independent of sector size
+const mbrSignature = 0xAA55        // Boot signature at end of MBR, 55h AAh on disk
+const mbrPartTypeProtective = 0xEE // MBR partition type of protective partition, which cover GPT disk
+
+var (
+	ErrMBRSignature        = errors.New("Bad MBR boot signature")
+	ErrLegacyMBR           = errors.New("MBR has no protective partition, it is legacy MBR")
+	ErrHybridMBR           = errors.New("MBR has partitions besides protective, it is hybrid MBR")
+	ErrProtectiveMBRLayout = errors.New("Protective partition doesn't cover the disk")
+)
+
+// https://en.wikipedia.org/wiki/Master_boot_record#Partition_table_entries
+type MBRPartition struct {
+	Status   byte    // Offset 0
+	CHSFirst [3]byte // Offset 1
+	Type     byte    // Offset 4
+	CHSLast  [3]byte // Offset 5
+	FirstLBA uint32  // Offset 8
+	Sectors  uint32  // Offset 12
+}
+
+func (this MBRPartition) IsEmpty() bool {
+	return this == MBRPartition{}
+}
+
+// https://en.wikipedia.org/wiki/GUID_Partition_Table#Protective_MBR_(LBA_0)
+type ProtectiveMBR struct {
+	BootCode      [440]byte       // Offset 0
+	DiskSignature uint32          // Offset 440
+	Reserved      uint16          // Offset 444
+	Partitions    [4]MBRPartition // Offset 446
+	Signature     uint16          // Offset 510. 0xAA55
+}
+
+// ReadProtectiveMBR - read MBR from first 512 bytes of the disk.
+func ReadProtectiveMBR(reader io.ReaderAt) (res ProtectiveMBR, err error) {
+	err = binary.Read(io.NewSectionReader(reader, 0, mbrSize), binary.LittleEndian, &res)
+	return res, err
+}
+
+// NewProtectiveMBR - MBR with single protective partition, which cover disk of diskSectors sectors.
+func NewProtectiveMBR(diskSectors uint64) ProtectiveMBR {
+	var res ProtectiveMBR
+	res.Partitions[0] = MBRPartition{
+		CHSFirst: [3]byte{0x00, 0x02, 0x00},
+		Type:     mbrPartTypeProtective,
+		CHSLast:  [3]byte{0xFF, 0xFF, 0xFF},
+		FirstLBA: 1,
+		Sectors:  protectiveMBRSectors(diskSectors),
+	}
+	res.Signature = mbrSignature
+	return res
+}
+
+func (this ProtectiveMBR) write(writer io.Writer) error {
+	return binary.Write(writer, binary.LittleEndian, &this)
+}
+
+// Validate - check the MBR is protective: it has boot signature, 0xEE partition record and other records are zeroed.
+// Return all found problems, empty result mean the MBR is protective. Size of protective partition isn't checked.
+func (this ProtectiveMBR) Validate() []error {
+	var res []error
+	if this.Signature != mbrSignature {
+		res = append(res, fmt.Errorf("%w: %#04x", ErrMBRSignature, this.Signature))
+	}
+	protectiveIndex := this.protectiveIndex()
+	if protectiveIndex == -1 {
+		res = append(res, ErrLegacyMBR)
+	}
+	for i, p := range this.Partitions {
+		if i != protectiveIndex && !p.IsEmpty() {
+			res = append(res, fmt.Errorf("%w: partition %v has type %#02x", ErrHybridMBR, i, p.Type))
+		}
+	}
+	return res
+}
+
+// Index of first protective partition record or -1
+func (this ProtectiveMBR) protectiveIndex() int {
+	for i, p := range this.Partitions {
+		if p.Type == mbrPartTypeProtective {
+			return i
+		}
+	}
+	return -1
+}
+
+// RequireProtectiveMBR - check the disk has protective MBR: exactly one 0xEE partition, which cover the disk,
+// and boot signature. Legacy and hybrid MBRs are rejected.
+func (this Table) RequireProtectiveMBR(reader io.ReaderAt) error {
+	mbr, err := ReadProtectiveMBR(reader)
+	if err != nil {
+		return err
+	}
+	if errs := mbr.Validate(); len(errs) != 0 {
+		return errs[0]
+	}
+
+	protective := mbr.Partitions[mbr.protectiveIndex()]
+	sectors := protectiveMBRSectors(this.diskSectors())
+	if protective.FirstLBA != 1 || (protective.Sectors != sectors && protective.Sectors != 0xFFFFFFFF) {
+		return fmt.Errorf("%w: start %v, size %v sectors, expected start 1, size %v sectors", ErrProtectiveMBRLayout,
+			protective.FirstLBA, protective.Sectors, sectors)
+	}
+	return nil
+}
+
+// Size of protective partition: from LBA 1 to end of the disk, limited by 32 bit
+func protectiveMBRSectors(diskSectors uint64) uint32 {
+	if diskSectors-1 > 0xFFFFFFFF {
+		return 0xFFFFFFFF
+	}
+	return uint32(diskSectors - 1)
+}
+
+// Disk size in sectors: last sector is backup header
+func (this Table) diskSectors() uint64 {
+	return this.BackupHeaderLBA() + 1
+}
@@ -0,0 +1,115 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testMBRBytes(t *testing.T, partitions ...MBRPartition) []byte {
+	mbr := ProtectiveMBR{Signature: mbrSignature}
+	copy(mbr.Partitions[:], partitions)
+	buf := &bytes.Buffer{}
+	if err := mbr.write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != mbrSize {
+		t.Fatal("MBR size: ", buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func TestRequireProtectiveMBR(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	protective := MBRPartition{Type: mbrPartTypeProtective, FirstLBA: 1, Sectors: 1024*1024*10/512 - 1}
+	linux := MBRPartition{Type: 0x83, FirstLBA: 2048, Sectors: 2048}
+
+	mbr := testMBRBytes(t, protective)
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr)); err != nil {
+		t.Error(err)
+	}
+	if m, err := ReadProtectiveMBR(bytes.NewReader(mbr)); err != nil || m.Partitions[0] != protective {
+		t.Error(m, err)
+	}
+
+	// legacy
+	mbr = testMBRBytes(t, linux)
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr)); !errors.Is(err, ErrLegacyMBR) {
+		t.Error(err)
+	}
+
+	// hybrid
+	mbr = testMBRBytes(t, protective, linux)
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr)); !errors.Is(err, ErrHybridMBR) {
+		t.Error(err)
+	}
+
+	// not whole disk
+	small := protective
+	small.Sectors = 100
+	mbr = testMBRBytes(t, small)
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr)); !errors.Is(err, ErrProtectiveMBRLayout) {
+		t.Error(err)
+	}
+
+	// no boot signature
+	mbr = testMBRBytes(t, protective)
+	mbr[510] = 0
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr)); !errors.Is(err, ErrMBRSignature) {
+		t.Error(err)
+	}
+
+	if err := table.RequireProtectiveMBR(bytes.NewReader(mbr[:100])); err == nil {
+		t.Error("Short MBR")
+	}
+}
+
+func TestProtectiveMBRValidate(t *testing.T) {
+	mbr := NewProtectiveMBR(1024 * 1024)
+	if errs := mbr.Validate(); len(errs) != 0 {
+		t.Error(errs)
+	}
+
+	mbr.Partitions[2] = MBRPartition{Type: 0x83, FirstLBA: 2048, Sectors: 2048}
+	if errs := mbr.Validate(); len(errs) != 1 || !errors.Is(errs[0], ErrHybridMBR) {
+		t.Error(errs)
+	}
+
+	mbr.Partitions[0] = MBRPartition{}
+	mbr.Signature = 0
+	errs := mbr.Validate()
+	if len(errs) != 3 || !errors.Is(errs[0], ErrMBRSignature) || errs[1] != ErrLegacyMBR || !errors.Is(errs[2], ErrHybridMBR) {
+		t.Error(errs)
+	}
+}
+
+func TestWriteWithProtectiveMBR(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	table := NewTable(diskSize, nil)
+	if _, err := table.AddPartition(GUID_LINUX_FS, 1024*1024, 0, "data"); err != nil {
+		t.Fatal(err)
+	}
+	buf := &randomWriteBuffer{}
+	if err := table.WriteWithProtectiveMBR(buf, diskSize); err != nil {
+		t.Fatal(err)
+	}
+	if len(buf.buf) != diskSize {
+		t.Fatal(len(buf.buf))
+	}
+	mbr, err := ReadProtectiveMBR(bytes.NewReader(buf.buf))
+	if err != nil || len(mbr.Validate()) != 0 {
+		t.Error(err, mbr.Validate())
+	}
+	if err = table.RequireProtectiveMBR(bytes.NewReader(buf.buf)); err != nil {
+		t.Error(err)
+	}
+	read, err := ReadBothTables(bytes.NewReader(buf.buf), 512)
+	if err != nil || !AreClones(read.Table, table) {
+		t.Error(err)
+	}
+
+	buf = &randomWriteBuffer{}
+	if err = table.WriteWithProtectiveMBR(buf, diskSize-512); !errors.Is(err, ErrWriteOutOfDisk) || len(buf.buf) != 0 {
+		t.Error(err, len(buf.buf))
+	}
+}
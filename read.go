@@ -0,0 +1,382 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+)
+
+var (
+	ErrSectorSizeNotDetected = errors.New("GPT signature not found for sector sizes 512 and 4096")
+	ErrNotExact              = errors.New("Stored table differs from re-serialized")
+)
+
+// Sector sizes, which probed for find GPT header
+var probeSectorSizes = []uint64{512, 4096}
+
+// DetectSectorSize - find GPT header signature at LBA1 for 512 and 4096 bytes sectors and return the sector size.
+func DetectSectorSize(reader io.ReaderAt) (uint64, error) {
+	signature := make([]byte, len(gptSignature))
+	for _, sectorSize := range probeSectorSizes {
+		_, err := reader.ReadAt(signature, int64(sectorSize))
+		if err == nil && string(signature) == gptSignature {
+			return sectorSize, nil
+		}
+	}
+	return 0, ErrSectorSizeNotDetected
+}
+
+// ReadTableFromFile - open disk or image file, detect its sector size and read primary GPT table.
+func ReadTableFromFile(path string) (table Table, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return table, err
+	}
+	defer f.Close()
+
+	sectorSize, err := DetectSectorSize(f)
+	if err != nil {
+		return table, err
+	}
+	_, err = f.Seek(int64(sectorSize), io.SeekStart)
+	if err != nil {
+		return table, err
+	}
+	return ReadTable(f, sectorSize)
+}
+
+// ReadTableAt - read primary table from LBA1 of reader. Offsets are relative to the reader, so *io.SectionReader
+// can be used for read disk image inside of bigger file.
+func ReadTableAt(reader io.ReaderAt, sectorSize uint64) (Table, error) {
+	seeker := io.NewSectionReader(reader, 0, math.MaxInt64)
+	if _, err := seeker.Seek(int64(sectorSize), io.SeekStart); err != nil {
+		return Table{}, err
+	}
+	return ReadTable(seeker, sectorSize)
+}
+
+// ReadPartition - read single entry of partitions array, described by the header, without read whole array.
+// Partitions CRC isn't checked.
+func ReadPartition(reader io.ReaderAt, header Header, sectorSize uint64, index int) (Partition, error) {
+	if index < 0 || uint64(index) >= uint64(header.PartitionsArrLen) {
+		return Partition{}, fmt.Errorf("%w: %v, PartitionsArrLen %v", ErrBadPartitionIndex, index, header.PartitionsArrLen)
+	}
+	if header.PartitionEntrySize < standardPartitionEntrySize {
+		return Partition{}, fmt.Errorf("%w: %v", ErrBadEntrySize, header.PartitionEntrySize)
+	}
+	arrayStart, ok := mul(int64(header.PartitionsTableStartLBA), int64(sectorSize))
+	entryOffset := int64(index) * int64(header.PartitionEntrySize)
+	if !ok || arrayStart+entryOffset < arrayStart {
+		return Partition{}, fmt.Errorf("Seek overflow when read partition %v", index)
+	}
+	entry := io.NewSectionReader(reader, arrayStart+entryOffset, int64(header.PartitionEntrySize))
+	return readPartition(entry, header.PartitionEntrySize)
+}
+
+// PartitionChecksum - reset the hash, pass all sectors of the partition through it and return the sum.
+func (this Table) PartitionChecksum(reader io.ReaderAt, index int, h hash.Hash) ([]byte, error) {
+	if err := this.checkNonEmptyPartition(index); err != nil {
+		return nil, err
+	}
+	p := this.Partitions[index]
+	start, startOk := mul(int64(p.FirstLBA), int64(this.SectorSize))
+	size, sizeOk := mul(int64(p.SizeInSectors()), int64(this.SectorSize))
+	if !startOk || !sizeOk {
+		return nil, fmt.Errorf("Read offset overflow for partition %v", index)
+	}
+
+	h.Reset()
+	n, err := io.Copy(h, io.NewSectionReader(reader, start, size))
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("%w: read %v of %v bytes of partition %v", io.ErrUnexpectedEOF, n, size, index)
+	}
+	return h.Sum(nil), nil
+}
+
+// VerifyTable - check header and partitions CRCs of the table without build partitions list.
+// Reader have to be at start of the header, as for ReadTable. Error is returned for read problems and bad signature.
+func VerifyTable(reader io.ReadSeeker, sectorSize uint64) (headerOk, partitionsOk bool, err error) {
+	header, err := readHeader(reader, sectorSize)
+	headerOk = err == nil
+	if errors.Is(err, ErrBadHeaderCRC) {
+		err = nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	arrayStart, ok := mul(int64(sectorSize), int64(header.PartitionsTableStartLBA))
+	if !ok {
+		return headerOk, false, fmt.Errorf("Seek overflow when read partition tables")
+	}
+	if _, err = reader.Seek(arrayStart, io.SeekStart); err != nil {
+		return headerOk, false, err
+	}
+
+	var crc uint32
+	buf := make([]byte, sectorSize)
+	for left := uint64(header.PartitionsArrLen) * uint64(header.PartitionEntrySize); left > 0; {
+		chunk := buf
+		if left < uint64(len(chunk)) {
+			chunk = chunk[:left]
+		}
+		if _, err = io.ReadFull(reader, chunk); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("%w: %v bytes left", ErrTruncatedArray, left)
+			}
+			return headerOk, false, err
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, chunk)
+		left -= uint64(len(chunk))
+	}
+	return headerOk, crc == header.PartitionsCRC, nil
+}
+
+// ReadBackupTable - read backup table from last sector of the reader. The reader must have size of the disk.
+func ReadBackupTable(reader io.ReadSeeker, sectorSize uint64) (Table, error) {
+	if _, err := reader.Seek(-int64(sectorSize), io.SeekEnd); err != nil {
+		return Table{}, err
+	}
+	return ReadTable(reader, sectorSize)
+}
+
+// TablePair - primary table and TrailingBytes of its backup header, which can differ from primary header.
+// It is read by ReadBothTables.
+type TablePair struct {
+	Table                     Table  // Primary table
+	BackupHeaderTrailingBytes []byte // TrailingBytes of backup header. Nil - same as Table.Header.TrailingBytes
+}
+
+// PrimaryTrailingBytes - TrailingBytes of primary header.
+func (this TablePair) PrimaryTrailingBytes() []byte {
+	return this.Table.Header.TrailingBytes
+}
+
+// BackupTrailingBytes - TrailingBytes of backup header.
+func (this TablePair) BackupTrailingBytes() []byte {
+	if this.BackupHeaderTrailingBytes == nil {
+		return this.Table.Header.TrailingBytes
+	}
+	return this.BackupHeaderTrailingBytes
+}
+
+// BackupTable - copy of the table on other side of the disk with backup header TrailingBytes.
+func (this TablePair) BackupTable() Table {
+	res := this.Table.CreateOtherSideTable()
+	res.Header.TrailingBytes = append([]byte(nil), this.BackupTrailingBytes()...)
+	res.Header.CRC = res.Header.calcCRC()
+	return res
+}
+
+// WriteToDisk - same as Table.WriteToDisk, but keep TrailingBytes of each header.
+func (this TablePair) WriteToDisk(writer io.WriteSeeker, diskSizeBytes uint64) error {
+	tables := []Table{this.Table, this.BackupTable()}
+	for _, table := range tables {
+		if err := table.checkWriteEnd(diskSizeBytes); err != nil {
+			return err
+		}
+	}
+	for _, table := range tables {
+		if err := table.Write(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBothTables - read primary table from LBA 1 and its backup copy from HeaderCopyStartLBA.
+// TrailingBytes of the headers are kept independently. The tables must be clones.
+func ReadBothTables(reader io.ReadSeeker, sectorSize uint64) (TablePair, error) {
+	if _, err := reader.Seek(int64(sectorSize), io.SeekStart); err != nil {
+		return TablePair{}, err
+	}
+	primary, err := ReadTable(reader, sectorSize)
+	if err != nil {
+		return TablePair{Table: primary}, fmt.Errorf("read primary table: %w", err)
+	}
+	backupOffset, ok := mul(int64(primary.Header.HeaderCopyStartLBA), int64(sectorSize))
+	if !ok {
+		return TablePair{Table: primary}, fmt.Errorf("Seek overflow when read backup table")
+	}
+	if _, err = reader.Seek(backupOffset, io.SeekStart); err != nil {
+		return TablePair{Table: primary}, err
+	}
+	backup, err := ReadTable(reader, sectorSize)
+	if err != nil {
+		return TablePair{Table: primary}, fmt.Errorf("read backup table: %w", err)
+	}
+	if !AreClones(primary, backup) {
+		return TablePair{Table: primary}, fmt.Errorf("Backup table at LBA %v isn't copy of primary table", primary.Header.HeaderCopyStartLBA)
+	}
+	return TablePair{Table: primary, BackupHeaderTrailingBytes: backup.Header.TrailingBytes}, nil
+}
+
+// ReadTableAutoSeeker - detect sector size by GPT signature and read primary table.
+// Reader can be at any position, it is restored on error.
+func ReadTableAutoSeeker(reader io.ReadSeeker) (table Table, err error) {
+	startPos, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return table, err
+	}
+	defer func() {
+		if err != nil {
+			reader.Seek(startPos, io.SeekStart)
+		}
+	}()
+
+	sectorSize, err := DetectSectorSize(readSeekerAt{reader})
+	if err != nil {
+		return table, err
+	}
+	if _, err = reader.Seek(int64(sectorSize), io.SeekStart); err != nil {
+		return table, err
+	}
+	return ReadTable(reader, sectorSize)
+}
+
+// io.ReaderAt over io.ReadSeeker, it changes position of the reader.
+type readSeekerAt struct {
+	io.ReadSeeker
+}
+
+func (this readSeekerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if _, err = this.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(this.ReadSeeker, p)
+}
+
+// ReadTableAutoDevice - read primary table from the device with its logical sector size (requested from OS on linux).
+// If the sector size can't be requested (not linux or f is image file) - it detected by DetectSectorSize.
+func ReadTableAutoDevice(f *os.File) (Table, error) {
+	sectorSize, err := deviceSectorSize(f)
+	if err != nil {
+		sectorSize, err = DetectSectorSize(f)
+	}
+	if err != nil {
+		return Table{}, err
+	}
+	if _, err = f.Seek(int64(sectorSize), io.SeekStart); err != nil {
+		return Table{}, err
+	}
+	return ReadTable(f, sectorSize)
+}
+
+// ReadTableExact - same as ReadTable, but also check Write of the table will save same bytes as read:
+// header and partitions array are byte-for-byte equal to stored. Return ErrNotExact if not.
+func ReadTableExact(reader io.ReadSeeker, SectorSize uint64) (Table, error) {
+	table, err := ReadTable(reader, SectorSize)
+	if err != nil {
+		return table, err
+	}
+	diffOffset, err := table.roundTripDiff(reader)
+	if err != nil {
+		return table, err
+	}
+	if diffOffset >= 0 {
+		return table, fmt.Errorf("%w: first different byte at offset %v", ErrNotExact, diffOffset)
+	}
+	return table, nil
+}
+
+// VerifyRoundTrip - read table from current position of reader (as ReadTable) and compare bytes, which Write save
+// for the table, with bytes stored in reader. Return false and offset of first different byte if they differ,
+// firstDiffOffset is -1 for stable table.
+func VerifyRoundTrip(reader io.ReadSeeker, sectorSize uint64) (stable bool, firstDiffOffset int64, err error) {
+	table, err := ReadTable(reader, sectorSize)
+	if err != nil {
+		return false, -1, err
+	}
+	firstDiffOffset, err = table.roundTripDiff(reader)
+	if err != nil {
+		return false, -1, err
+	}
+	return firstDiffOffset < 0, firstDiffOffset, nil
+}
+
+// Compare bytes, which Write save for the table, with bytes stored in reader at same offsets.
+// Return offset of first different byte or -1 if all bytes are equal.
+func (this Table) roundTripDiff(reader io.ReadSeeker) (int64, error) {
+	header, err := this.serializedHeader()
+	if err != nil {
+		return 0, err
+	}
+	partitions := &bytes.Buffer{}
+	for _, part := range this.Partitions {
+		if err = part.write(partitions, this.Header.PartitionEntrySize); err != nil {
+			return 0, err
+		}
+	}
+
+	regions := []struct {
+		lba  uint64
+		data []byte
+	}{
+		{this.Header.HeaderStartLBA, header},
+		{this.Header.PartitionsTableStartLBA, partitions.Bytes()},
+	}
+	for _, region := range regions {
+		offset, ok := mul(int64(region.lba), int64(this.SectorSize))
+		if !ok {
+			return 0, fmt.Errorf("Seek overflow when compare table")
+		}
+		if _, err = reader.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		stored := make([]byte, len(region.data))
+		n, err := io.ReadFull(reader, stored)
+		for i := 0; i < n; i++ {
+			if stored[i] != region.data[i] {
+				return offset + int64(i), nil
+			}
+		}
+		if err != nil {
+			return offset + int64(n), nil
+		}
+	}
+	return -1, nil
+}
+
+// Result of read table from one file by ReadTables
+type TableOrError struct {
+	Table Table
+	Err   error
+}
+
+// ReadTables - read tables from many files by ReadTableFromFile in parallel (up to GOMAXPROCS files at once).
+// Error of one file doesn't stop read others, it is stored in result for the file.
+func ReadTables(paths []string) map[string]TableOrError {
+	res := make(map[string]TableOrError, len(paths))
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				table, err := ReadTableFromFile(path)
+				mu.Lock()
+				res[path] = TableOrError{Table: table, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	return res
+}
@@ -0,0 +1,381 @@
+package gpt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Disk image start with new empty table with 4096 bytes sector
+func testDisk4KBuf(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	if _, err := NewTable(4096*1024*1024, &NewTableArgs{SectorSize: 4096}).WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectSectorSize(t *testing.T) {
+	sectorSize, err := DetectSectorSize(bytes.NewReader(testDiskBuf()))
+	if err != nil || sectorSize != 512 {
+		t.Error(sectorSize, err)
+	}
+
+	sectorSize, err = DetectSectorSize(bytes.NewReader(testDisk4KBuf(t)))
+	if err != nil || sectorSize != 4096 {
+		t.Error(sectorSize, err)
+	}
+
+	_, err = DetectSectorSize(bytes.NewReader(make([]byte, 8192)))
+	if !errors.Is(err, ErrSectorSizeNotDetected) {
+		t.Error(err)
+	}
+}
+
+func TestReadTableFromFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gpt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(testDisk4KBuf(t))
+	f.Close()
+
+	table, err := ReadTableFromFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.SectorSize != 4096 || len(table.Partitions) != 128 {
+		t.Error(table.SectorSize, len(table.Partitions))
+	}
+
+	if _, err = ReadTableFromFile(f.Name() + "-not-exist"); err == nil {
+		t.Error("Read not existed file")
+	}
+}
+
+func TestReadTableSectionReader(t *testing.T) {
+	const offset = 1024 * 1024
+	disk := testDiskBuf()
+	file := append(make([]byte, offset), disk...)
+	file = append(file, make([]byte, offset)...)
+	section := io.NewSectionReader(bytes.NewReader(file), offset, int64(len(disk)))
+
+	if _, err := section.Seek(512, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	table, err := ReadTable(section, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[2].Name() != "primary" {
+		t.Error(table.Partitions[2].Name())
+	}
+
+	table, err = ReadTableAt(section, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Partitions[2].Name() != "primary" {
+		t.Error(table.Partitions[2].Name())
+	}
+
+	if _, err = ReadTableAt(io.NewSectionReader(bytes.NewReader(file), 0, int64(len(file))), 512); err == nil {
+		t.Error("Read table from start of file")
+	}
+}
+
+func TestPartitionChecksum(t *testing.T) {
+	table := NewTable(1024*1024*4, nil)
+	table.Partitions[0] = Partition{Type: GUID_LINUX_FS, FirstLBA: 2048, LastLBA: 4095}
+	disk := make([]byte, 1024*1024*4)
+	content := disk[2048*512 : 4096*512]
+	for i := range content {
+		content[i] = byte(i)
+	}
+	disk[2048*512-1], disk[4096*512] = 1, 1 // Around the partition
+
+	sum, err := table.PartitionChecksum(bytes.NewReader(disk), 0, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sha256.Sum256(content); !bytes.Equal(sum, expected[:]) {
+		t.Error("Bad checksum")
+	}
+
+	if _, err = table.PartitionChecksum(bytes.NewReader(disk), 1, sha256.New()); !errors.Is(err, ErrEmptyPartition) {
+		t.Error(err)
+	}
+	if _, err = table.PartitionChecksum(bytes.NewReader(disk[:3000*512]), 0, sha256.New()); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTable(t *testing.T) {
+	buf := testDiskBuf()
+	reader := bytes.NewReader(buf)
+	reader.Seek(512, io.SeekStart)
+	headerOk, partitionsOk, err := VerifyTable(reader, 512)
+	if err != nil || !headerOk || !partitionsOk {
+		t.Error(headerOk, partitionsOk, err)
+	}
+
+	buf[1024+32]++ // FirstLBA of first partition
+	reader.Seek(512, io.SeekStart)
+	headerOk, partitionsOk, err = VerifyTable(reader, 512)
+	if err != nil || !headerOk || partitionsOk {
+		t.Error("Partitions: ", headerOk, partitionsOk, err)
+	}
+
+	buf[512+24]++ // HeaderStartLBA
+	reader.Seek(512, io.SeekStart)
+	headerOk, partitionsOk, err = VerifyTable(reader, 512)
+	if err != nil || headerOk || partitionsOk {
+		t.Error("Header: ", headerOk, partitionsOk, err)
+	}
+
+	reader = bytes.NewReader(buf[:1024+100])
+	reader.Seek(512, io.SeekStart)
+	if _, _, err = VerifyTable(reader, 512); !errors.Is(err, ErrTruncatedArray) {
+		t.Error(err)
+	}
+}
+
+func BenchmarkVerifyTable(b *testing.B) {
+	reader := bytes.NewReader(testDiskBuf())
+	for i := 0; i < b.N; i++ {
+		reader.Seek(512, io.SeekStart)
+		if _, _, err := VerifyTable(reader, 512); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadTableAutoSeeker(t *testing.T) {
+	reader := bytes.NewReader(testDisk4KBuf(t))
+	table, err := ReadTableAutoSeeker(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.SectorSize != 4096 || table.Header.HeaderStartLBA != 1 || len(table.Partitions) != 128 {
+		t.Error(table.SectorSize, table.Header.HeaderStartLBA, len(table.Partitions))
+	}
+
+	reader = bytes.NewReader(testDiskBuf())
+	if table, err = ReadTableAutoSeeker(reader); err != nil || table.SectorSize != 512 {
+		t.Error(table.SectorSize, err)
+	}
+
+	reader = bytes.NewReader(make([]byte, 10000))
+	reader.Seek(100, 0)
+	if _, err = ReadTableAutoSeeker(reader); !errors.Is(err, ErrSectorSizeNotDetected) {
+		t.Error(err)
+	}
+	if pos, _ := reader.Seek(0, 1); pos != 100 {
+		t.Error("Position isn't restored: ", pos)
+	}
+}
+
+func TestReadTableAutoDevice(t *testing.T) {
+	f, err := ioutil.TempFile("", "gpt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write(testDisk4KBuf(t))
+
+	if runtime.GOOS == "linux" {
+		if _, err = deviceSectorSize(f); err == nil {
+			t.Error("Sector size of regular file")
+		}
+	}
+
+	// Fallback to detect sector size by signature
+	table, err := ReadTableAutoDevice(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.SectorSize != 4096 {
+		t.Error(table.SectorSize)
+	}
+}
+
+func TestReadTableExact(t *testing.T) {
+	fixture, err := readHeader(bytes.NewReader(GPT_TEST_HEADER), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := []func(h *Header){
+		func(h *Header) {},
+		func(h *Header) { h.Reserved = 0xDEADBEEF },
+		func(h *Header) {
+			for i := range h.TrailingBytes {
+				h.TrailingBytes[i] = byte(i)
+			}
+		},
+		func(h *Header) {
+			h.Size = 512 // Trailing bytes are covered by CRC
+			h.TrailingBytes[10] = 1
+			h.TrailingBytes[len(h.TrailingBytes)-1] = 2
+		},
+		func(h *Header) {
+			h.Revision = 0x10003
+			h.Size = 100
+			h.TrailingBytes[0] = 7
+		},
+	}
+	for i, modify := range headers {
+		h := fixture
+		h.TrailingBytes = append([]byte(nil), fixture.TrailingBytes...)
+		modify(&h)
+		headerBuf := &bytes.Buffer{}
+		h.write(headerBuf, true)
+
+		buf := testDiskBuf()
+		copy(buf[512:], headerBuf.Bytes())
+		reader := bytes.NewReader(buf)
+		reader.Seek(512, 0)
+		table, err := ReadTableExact(reader, 512)
+		if err != nil {
+			t.Error(i, err)
+			continue
+		}
+		if !HeadersEqual(h, table.Header) {
+			t.Error("Header changed: ", i)
+		}
+		writeBuf := &randomWriteBuffer{}
+		table.Write(writeBuf)
+		if !bytes.Equal(buf, writeBuf.buf) {
+			t.Error("Write differs: ", i)
+		}
+	}
+
+	// Header stored not at HeaderStartLBA
+	buf := testDiskBuf()
+	buf = append(buf, GPT_TEST_HEADER...)
+	for i := 512; i < 1024; i++ {
+		buf[i] = 0
+	}
+	reader := bytes.NewReader(buf)
+	reader.Seek(int64(len(buf)-len(GPT_TEST_HEADER)), 0)
+	if _, err := ReadTableExact(reader, 512); !errors.Is(err, ErrNotExact) || !strings.Contains(err.Error(), "offset 512") {
+		t.Error(err)
+	}
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	reader := bytes.NewReader(testDiskBuf())
+	reader.Seek(512, 0)
+	if stable, offset, err := VerifyRoundTrip(reader, 512); !stable || offset != -1 || err != nil {
+		t.Error(stable, offset, err)
+	}
+
+	// Header stored not at HeaderStartLBA
+	buf := testDiskBuf()
+	buf = append(buf, GPT_TEST_HEADER...)
+	for i := 512; i < 1024; i++ {
+		buf[i] = 0
+	}
+	reader = bytes.NewReader(buf)
+	reader.Seek(int64(len(buf)-len(GPT_TEST_HEADER)), 0)
+	if stable, offset, err := VerifyRoundTrip(reader, 512); stable || offset != 512 || err != nil {
+		t.Error(stable, offset, err)
+	}
+
+	if _, _, err := VerifyRoundTrip(bytes.NewReader(make([]byte, 1024)), 512); err == nil {
+		t.Error("Read empty disk")
+	}
+}
+
+func TestReadTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.img")
+	bad := filepath.Join(dir, "bad.img")
+	ioutil.WriteFile(good, testDiskBuf(), 0600)
+	ioutil.WriteFile(bad, bytes.Repeat([]byte("garbage"), 1000), 0600)
+	notExisted := filepath.Join(dir, "not-existed.img")
+
+	res := ReadTables([]string{good, bad, notExisted})
+	if len(res) != 3 {
+		t.Fatal(res)
+	}
+	if res[good].Err != nil || res[good].Table.Header.DiskGUID != readTestTable(t).Header.DiskGUID {
+		t.Error("Good: ", res[good].Err)
+	}
+	if !errors.Is(res[bad].Err, ErrSectorSizeNotDetected) {
+		t.Error("Bad: ", res[bad].Err)
+	}
+	if res[notExisted].Err == nil {
+		t.Error("Not existed file")
+	}
+}
+
+func TestReadBothTables(t *testing.T) {
+	const diskSize = 1024 * 1024 * 10
+	image, err := NewTable(diskSize, nil).MinimalImage(diskSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image[512+standardHeaderSize] = 1
+	image[diskSize-512+standardHeaderSize] = 2
+
+	pair, err := ReadBothTables(bytes.NewReader(image), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.PrimaryTrailingBytes()[0] != 1 || pair.BackupTrailingBytes()[0] != 2 {
+		t.Error(pair.PrimaryTrailingBytes()[0], pair.BackupTrailingBytes()[0])
+	}
+	if backup := pair.BackupTable(); !backup.IsBackupHeader() || backup.Header.TrailingBytes[0] != 2 {
+		t.Error("Backup table")
+	}
+	if pair.Table.CreateOtherSideTable().Header.TrailingBytes[0] != 1 {
+		t.Error("Table has hidden state")
+	}
+
+	written := &randomWriteBuffer{buf: make([]byte, diskSize)}
+	copy(written.buf, image[:512])
+	if err = pair.WriteToDisk(written, diskSize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written.buf, image) {
+		t.Error("Trailing bytes aren't kept by write")
+	}
+
+	pair.BackupHeaderTrailingBytes = nil
+	if pair.BackupTrailingBytes()[0] != 1 {
+		t.Error("Nil backup trailing bytes")
+	}
+}
+
+func TestReadPartition(t *testing.T) {
+	table := readTestTable(t)
+	reader := bytes.NewReader(testDiskBuf())
+	for _, index := range []int{0, 2, 127} {
+		p, err := ReadPartition(reader, table.Header, 512, index)
+		if err != nil || !reflect.DeepEqual(p, table.Partitions[index]) {
+			t.Error(index, p, err)
+		}
+	}
+	if _, err := ReadPartition(reader, table.Header, 512, 128); !errors.Is(err, ErrBadPartitionIndex) {
+		t.Error(err)
+	}
+	if _, err := ReadPartition(bytes.NewReader(testDiskBuf()[:1100]), table.Header, 512, 0); err == nil {
+		t.Error("Truncated array")
+	}
+}
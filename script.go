@@ -0,0 +1,53 @@
+package gpt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PartedScript - shell script of parted commands, which recreate the partitions layout on devPath.
+// Partitions are created in order of the table slots, numbers of parted partitions are sequential from 1.
+// Partition types are set by "type" command, which needs parted 3.5 or newer.
+func (this Table) PartedScript(devPath string) string {
+	res := &strings.Builder{}
+	parted := "parted --script " + shellQuote(devPath)
+	fmt.Fprintf(res, "%v mklabel gpt\n", parted)
+
+	number := 0
+	for _, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		number++
+		fmt.Fprintf(res, "%v unit s mkpart %v %vs %vs\n", parted, shellQuote(`"`+p.Name()+`"`), p.FirstLBA, p.LastLBA)
+		fmt.Fprintf(res, "%v type %v %v\n", parted, number, p.Type)
+		attributes := p.Attributes()
+		// parted save "hidden" flag of GPT partition as RequiredPartition attribute
+		if attributes.RequiredPartition() {
+			fmt.Fprintf(res, "%v set %v hidden on\n", parted, number)
+		}
+		if attributes.LegacyBIOSBootable() {
+			fmt.Fprintf(res, "%v set %v legacy_boot on\n", parted, number)
+		}
+	}
+	return res.String()
+}
+
+// LosetupScript - shell script of losetup commands, which attach every non-empty partition of image file
+// to free loop device and print the device name. Offset and size limit are in bytes.
+func (this Table) LosetupScript(imagePath string) string {
+	res := &strings.Builder{}
+	for _, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		fmt.Fprintf(res, "losetup --find --show --offset %v --sizelimit %v %v\n",
+			p.FirstLBA*this.SectorSize, p.SizeInSectors()*this.SectorSize, shellQuote(imagePath))
+	}
+	return res.String()
+}
+
+// Quote s for POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
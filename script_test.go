@@ -0,0 +1,51 @@
+package gpt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPartedScript(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[2].SetAttributes(Attributes(0).With(FlagLegacyBIOSBootable, true))
+	lines := strings.Split(table.PartedScript("/dev/sda"), "\n")
+	if len(lines) != 9 || lines[8] != "" {
+		t.Fatal(lines)
+	}
+	if lines[0] != "parted --script '/dev/sda' mklabel gpt" {
+		t.Error(lines[0])
+	}
+	esp := `parted --script '/dev/sda' unit s mkpart '"` + table.Partitions[0].Name() + `"' 2048s 780287s`
+	if lines[1] != esp {
+		t.Error(lines[1])
+	}
+	if lines[2] != "parted --script '/dev/sda' type 1 C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Error(lines[2])
+	}
+	if lines[5] != `parted --script '/dev/sda' unit s mkpart '"primary"' 80781312s 1953525134s` {
+		t.Error(lines[5])
+	}
+	if lines[7] != "parted --script '/dev/sda' set 3 legacy_boot on" {
+		t.Error(lines[7])
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if res := shellQuote("it's"); res != `'it'\''s'` {
+		t.Error(res)
+	}
+}
+
+func TestLosetupScript(t *testing.T) {
+	table := readTestTable(t)
+	lines := strings.Split(table.LosetupScript("/tmp/disk's.img"), "\n")
+	if len(lines) != 4 || lines[3] != "" {
+		t.Fatal(lines)
+	}
+	if lines[0] != `losetup --find --show --offset 1048576 --sizelimit 398458880 '/tmp/disk'\''s.img'` {
+		t.Error(lines[0])
+	}
+	if lines[2] != "losetup --find --show --offset 41360031744 --sizelimit 958844837376 '/tmp/disk'\\''s.img'" {
+		t.Error(lines[2])
+	}
+}
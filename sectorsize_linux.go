@@ -0,0 +1,22 @@
+//go:build linux
+// +build linux
+
+package gpt
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const ioctlBLKSSZGET = 0x1268 // Get logical sector size of block device
+
+// Logical sector size of block device
+func deviceSectorSize(f *os.File) (uint64, error) {
+	var size int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlBLKSSZGET, uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return uint64(size), nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package gpt
+
+import (
+	"errors"
+	"os"
+)
+
+// Logical sector size of block device
+func deviceSectorSize(f *os.File) (uint64, error) {
+	return 0, errors.New("Get device sector size isn't supported on the OS")
+}
@@ -0,0 +1,201 @@
+package gpt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrBadSfdiskDump = errors.New("Bad sfdisk dump")
+)
+
+// SfdiskDump - the table in format of "sfdisk --dump devPath". Partition devices are named by slot numbers as by Linux
+// kernel: /dev/sda1 for /dev/sda, /dev/nvme0n1p1 for /dev/nvme0n1.
+func (this Table) SfdiskDump(devPath string) string {
+	res := &strings.Builder{}
+	fmt.Fprintf(res, "label: gpt\n")
+	fmt.Fprintf(res, "label-id: %v\n", this.Header.DiskGUID)
+	fmt.Fprintf(res, "device: %v\n", devPath)
+	fmt.Fprintf(res, "unit: sectors\n")
+	fmt.Fprintf(res, "first-lba: %v\n", this.Header.FirstUsableLBA)
+	fmt.Fprintf(res, "last-lba: %v\n", this.Header.LastUsableLBA)
+	if this.SectorSize != 512 {
+		fmt.Fprintf(res, "sector-size: %v\n", this.SectorSize)
+	}
+	fmt.Fprintf(res, "\n")
+
+	for i, p := range this.Partitions {
+		if p.IsEmpty() {
+			continue
+		}
+		fmt.Fprintf(res, "%v : start=%12v, size=%12v, type=%v, uuid=%v", partitionDevice(devPath, i), p.FirstLBA,
+			p.SizeInSectors(), p.Type, p.Id)
+		if name := p.Name(); name != "" {
+			fmt.Fprintf(res, `, name="%v"`, strings.NewReplacer(`\`, `\x5c`, `"`, `\x22`).Replace(name))
+		}
+		if attrs := sfdiskAttrs(p.Attributes()); attrs != "" {
+			fmt.Fprintf(res, `, attrs="%v"`, attrs)
+		}
+		fmt.Fprintf(res, "\n")
+	}
+	return res.String()
+}
+
+// Device path of partition with index of the table slot
+func partitionDevice(devPath string, index int) string {
+	if devPath != "" && unicode.IsDigit(rune(devPath[len(devPath)-1])) {
+		return fmt.Sprintf("%vp%v", devPath, index+1)
+	}
+	return fmt.Sprintf("%v%v", devPath, index+1)
+}
+
+// Attributes as sfdisk write them: names of known bits and GUID:N for others
+func sfdiskAttrs(attributes Attributes) string {
+	var names []string
+	for bit := uint(0); bit < 64; bit++ {
+		if !attributes.Has(bit) {
+			continue
+		}
+		if name, ok := flagNames[bit]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("GUID:%v", bit))
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// ParseSfdiskDump - create table for disk of diskSizeBytes from output of "sfdisk --dump". Partitions are placed
+// to slots by numbers of their devices. Unknown lines and fields are ignored. Partition type have to be GUID.
+func ParseSfdiskDump(reader io.Reader, diskSizeBytes uint64, sectorSize uint64) (Table, error) {
+	table := NewTable(diskSizeBytes, &NewTableArgs{SectorSize: sectorSize})
+	scanner := bufio.NewScanner(reader)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		var err error
+		if strings.Contains(line, " : ") {
+			err = table.parseSfdiskPartition(line)
+		} else if pos := strings.Index(line, ":"); pos != -1 {
+			err = table.parseSfdiskHeader(strings.TrimSpace(line[:pos]), strings.TrimSpace(line[pos+1:]))
+		}
+		if err != nil {
+			return Table{}, fmt.Errorf("line %v: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Table{}, err
+	}
+	table.Touch()
+	return table, nil
+}
+
+func (this *Table) parseSfdiskHeader(key, value string) (err error) {
+	switch key {
+	case "label":
+		if value != "gpt" {
+			return fmt.Errorf("%w: label %q", ErrBadSfdiskDump, value)
+		}
+	case "label-id":
+		this.Header.DiskGUID, err = StringToGuid(value)
+	case "first-lba":
+		this.Header.FirstUsableLBA, err = strconv.ParseUint(value, 10, 64)
+	case "last-lba":
+		this.Header.LastUsableLBA, err = strconv.ParseUint(value, 10, 64)
+	case "sector-size":
+		if value != strconv.FormatUint(this.SectorSize, 10) {
+			return fmt.Errorf("%w: sector size %v, expected %v", ErrBadSfdiskDump, value, this.SectorSize)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrBadSfdiskDump, key, err)
+	}
+	return nil
+}
+
+func (this *Table) parseSfdiskPartition(line string) error {
+	pos := strings.Index(line, " : ")
+	device := strings.TrimSpace(line[:pos])
+	numberStart := strings.LastIndexFunc(device, func(r rune) bool { return !unicode.IsDigit(r) }) + 1
+	number, err := strconv.Atoi(device[numberStart:])
+	if err != nil || number < 1 || number > len(this.Partitions) {
+		return fmt.Errorf("%w: partition number of %q", ErrBadSfdiskDump, device)
+	}
+
+	p := Partition{Id: NewGUID(), TrailingBytes: this.entryTrailingBytes(nil)}
+	var size uint64
+	for _, field := range splitSfdiskFields(line[pos+3:]) {
+		eq := strings.Index(field, "=")
+		if eq == -1 {
+			continue
+		}
+		key, value := strings.TrimSpace(field[:eq]), strings.TrimSpace(field[eq+1:])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		switch key {
+		case "start":
+			p.FirstLBA, err = strconv.ParseUint(value, 10, 64)
+		case "size":
+			size, err = strconv.ParseUint(value, 10, 64)
+		case "type":
+			var guid Guid
+			guid, err = StringToGuid(value)
+			p.Type = PartType(guid)
+		case "uuid":
+			p.Id, err = StringToGuid(value)
+		case "name":
+			err = p.SetName(value)
+		case "attrs":
+			err = p.setSfdiskAttrs(value)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrBadSfdiskDump, key, err)
+		}
+	}
+	if p.IsEmpty() || size == 0 {
+		return fmt.Errorf("%w: partition %v without type or size", ErrBadSfdiskDump, number)
+	}
+	p.LastLBA = p.FirstLBA + size - 1
+	this.Partitions[number-1] = p
+	return nil
+}
+
+// Split partition fields by commas outside of quotes
+func splitSfdiskFields(s string) (res []string) {
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, s[start:])
+}
+
+func (this *Partition) setSfdiskAttrs(s string) error {
+	var attributes Attributes
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		var bit uint
+		if strings.HasPrefix(name, "GUID:") {
+			if _, err := fmt.Sscanf(name, "GUID:%d", &bit); err != nil || bit >= 64 {
+				return fmt.Errorf("%w: %q", ErrUnknownFlag, name)
+			}
+		} else if knownBit, ok := flagBitByName(name); ok {
+			bit = knownBit
+		} else {
+			return fmt.Errorf("%w: %q", ErrUnknownFlag, name)
+		}
+		attributes = attributes.With(bit, true)
+	}
+	this.SetAttributes(attributes)
+	return nil
+}
@@ -0,0 +1,81 @@
+package gpt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSfdiskDump(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].SetAttributes(Attributes(0).With(FlagLegacyBIOSBootable, true).With(60, true))
+	lines := strings.Split(table.SfdiskDump("/dev/sda"), "\n")
+	if len(lines) != 11 {
+		t.Fatal(lines)
+	}
+	if lines[0] != "label: gpt" || lines[1] != "label-id: 7C4E8BBE-A43A-489F-8E1C-05C45A2AA8BC" || lines[4] != "first-lba: 34" {
+		t.Error(lines[:7])
+	}
+
+	esp := "/dev/sda1 : start=        2048, size=      778240, type=C12A7328-F81F-11D2-BA4B-00A0C93EC93B, " +
+		"uuid=DC2F50B0-98DE-4681-A868-42E9FEBD6E3E"
+	if !strings.HasPrefix(lines[7], esp) {
+		t.Error(lines[7])
+	}
+	if !strings.HasSuffix(lines[8], `attrs="LegacyBIOSBootable GUID:60"`) {
+		t.Error(lines[8])
+	}
+	if !strings.HasPrefix(lines[9], "/dev/sda3 :") || !strings.HasSuffix(lines[9], `name="primary"`) {
+		t.Error(lines[9])
+	}
+
+	if res := partitionDevice("/dev/nvme0n1", 0); res != "/dev/nvme0n1p1" {
+		t.Error(res)
+	}
+}
+
+func TestParseSfdiskDump(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].SetAttributes(Attributes(0).With(FlagRequiredPartition, true).With(60, true))
+	table.Partitions[1].SetName(`name, with "quotes"`)
+	table.Partitions[5] = table.Partitions[2]
+	table.Partitions[2] = Partition{}
+	table.Touch()
+
+	dump := table.SfdiskDump("/dev/sda") + "unknown line\n"
+	parsed, err := ParseSfdiskDump(strings.NewReader(dump), testDiskSize, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.DiskGUID != table.Header.DiskGUID || parsed.Header.FirstUsableLBA != 34 ||
+		parsed.Header.LastUsableLBA != table.Header.LastUsableLBA {
+		t.Error("Header: ", parsed.Header)
+	}
+	for i := range table.Partitions {
+		expected, _ := table.PartitionEntryBytes(i)
+		if res, _ := parsed.PartitionEntryBytes(i); !bytes.Equal(res, expected) {
+			t.Error("Partition: ", i, parsed.Partitions[i])
+		}
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	for _, name := range []string{`C:\temp`, `x\x22y`, `\"`} {
+		table.Partitions[0].SetName(name)
+		parsed, err = ParseSfdiskDump(strings.NewReader(table.SfdiskDump("/dev/sda")), testDiskSize, 512)
+		if err != nil || parsed.Partitions[0].Name() != name {
+			t.Errorf("Name %q: %q, %v", name, parsed.Partitions[0].Name(), err)
+		}
+	}
+
+	_, err = ParseSfdiskDump(strings.NewReader("label: dos\n"), testDiskSize, 512)
+	if !errors.Is(err, ErrBadSfdiskDump) {
+		t.Error(err)
+	}
+	_, err = ParseSfdiskDump(strings.NewReader("/dev/sda1 : start=2048, size=100, type=bad\n"), testDiskSize, 512)
+	if !errors.Is(err, ErrBadSfdiskDump) || !strings.HasPrefix(err.Error(), "line 1: ") {
+		t.Error(err)
+	}
+}
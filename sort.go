@@ -0,0 +1,46 @@
+package gpt
+
+import "sort"
+
+// SortKey - order of partitions for SortPartitions
+type SortKey int
+
+const (
+	ByFirstLBA SortKey = iota
+	ByName
+	ByType
+	BySize
+)
+
+// SortPartitions - return sorted copy of partitions. Empty partitions are moved to the end.
+// Partitions with equal key are ordered by FirstLBA, then by original order.
+func SortPartitions(parts []Partition, by SortKey) []Partition {
+	res := make([]Partition, len(parts))
+	copy(res, parts)
+
+	less := func(a, b Partition) bool {
+		switch by {
+		case ByName:
+			if a.Name() != b.Name() {
+				return a.Name() < b.Name()
+			}
+		case ByType:
+			if a.Type != b.Type {
+				return a.Type.String() < b.Type.String()
+			}
+		case BySize:
+			if a.SizeInSectors() != b.SizeInSectors() {
+				return a.SizeInSectors() < b.SizeInSectors()
+			}
+		}
+		return a.FirstLBA < b.FirstLBA
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].IsEmpty() || res[j].IsEmpty() {
+			return !res[i].IsEmpty() && res[j].IsEmpty()
+		}
+		return less(res[i], res[j])
+	})
+	return res
+}
@@ -0,0 +1,49 @@
+package gpt
+
+import "testing"
+
+func TestSortPartitions(t *testing.T) {
+	parts := make([]Partition, 6)
+	parts[1] = Partition{Type: GUID_LVM, FirstLBA: 5000, LastLBA: 5999}
+	parts[1].SetName("b")
+	parts[3] = Partition{Type: GUID_LINUX_FS, FirstLBA: 2048, LastLBA: 4095}
+	parts[3].SetName("c")
+	parts[4] = Partition{Type: GUID_BIOS_BOOT, FirstLBA: 34, LastLBA: 2047}
+	parts[4].SetName("a")
+	parts[5] = Partition{Type: GUID_LVM, FirstLBA: 4096, LastLBA: 4999}
+	parts[5].SetName("b")
+
+	names := func(parts []Partition) (res string) {
+		for _, p := range parts {
+			if p.IsEmpty() {
+				res += "-"
+			} else {
+				res += p.Name() + p.Type.String()[:1]
+			}
+		}
+		return res
+	}
+
+	tests := []struct {
+		by       SortKey
+		expected string
+	}{
+		{ByFirstLBA, "a2c0bEbE--"},
+		{ByName, "a2bEbEc0--"},
+		{ByType, "c0a2bEbE--"},
+		{BySize, "bEbEa2c0--"},
+	}
+	for _, test := range tests {
+		sorted := SortPartitions(parts, test.by)
+		if names(sorted) != test.expected {
+			t.Errorf("%v: %v, expected %v", test.by, names(sorted), test.expected)
+		}
+	}
+
+	if sorted := SortPartitions(parts, ByName); sorted[1].FirstLBA != 4096 || sorted[2].FirstLBA != 5000 {
+		t.Error("Equal names must be ordered by FirstLBA")
+	}
+	if !parts[0].IsEmpty() || parts[1].FirstLBA != 5000 {
+		t.Error("Source changed")
+	}
+}
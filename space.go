@@ -0,0 +1,374 @@
+package gpt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const defaultAlignmentBytes = 1024 * 1024 // Default alignment of partitions, 1MiB
+const minAlignmentBytes = 4096            // Alignment of partitions without data, physical sector of modern disks
+
+// RecommendedAlignment - alignment of partition start in sectors for partition type: 4KiB for Microsoft reserved partition,
+// which has no data and doesn't need fast access, 1MiB for other types. It is at least one sector.
+func RecommendedAlignment(typ PartType, sectorSize uint64) uint64 {
+	alignmentBytes := uint64(defaultAlignmentBytes)
+	if typ == GUID_MSR {
+		alignmentBytes = minAlignmentBytes
+	}
+	if alignmentBytes <= sectorSize {
+		return 1
+	}
+	return alignmentBytes / sectorSize
+}
+
+// Default alignment (1MiB) in sectors, at least one sector. Zero sector size mean no alignment.
+func defaultAlignmentSectors(sectorSize uint64) uint64 {
+	if sectorSize == 0 || sectorSize >= defaultAlignmentBytes {
+		return 1
+	}
+	return defaultAlignmentBytes / sectorSize
+}
+
+// Range of free sectors, both bounds are included.
+type FreeRegion struct {
+	FirstLBA uint64
+	LastLBA  uint64
+}
+
+func (this FreeRegion) Sectors() uint64 {
+	return this.LastLBA - this.FirstLBA + 1
+}
+
+// MetadataSectors - sector ranges, occupied by primary header with partitions array and by backup partitions array with header.
+// Location of the other side array isn't stored in the header, it is supposed standard: right after primary header and
+// right before backup header.
+func (this Table) MetadataSectors() (primaryStart, primaryEnd, backupStart, backupEnd uint64) {
+	arraySectors := this.Header.partitionsTableSectors(this.SectorSize)
+
+	primaryStart, backupEnd = this.PrimaryHeaderLBA(), this.BackupHeaderLBA()
+	primaryArrayStart, backupArrayStart := this.Header.PartitionsTableStartLBA, backupEnd-arraySectors
+	if this.IsBackupHeader() {
+		primaryArrayStart, backupArrayStart = primaryStart+1, this.Header.PartitionsTableStartLBA
+	}
+
+	primaryEnd = primaryStart
+	if arraySectors > 0 && primaryArrayStart+arraySectors-1 > primaryEnd {
+		primaryEnd = primaryArrayStart + arraySectors - 1
+	}
+	backupStart = backupEnd
+	if arraySectors > 0 && backupArrayStart < backupStart {
+		backupStart = backupArrayStart
+	}
+	return primaryStart, primaryEnd, backupStart, backupEnd
+}
+
+// LastMetadataLBA - highest LBA, occupied by GPT metadata: the backup header. Sectors after it are free of GPT.
+// If the backup header is out of disk of diskSizeBytes, last LBA of the disk is returned. Zero diskSizeBytes means unknown
+// disk size.
+func (this Table) LastMetadataLBA(diskSizeBytes uint64) uint64 {
+	res := this.BackupHeaderLBA()
+	if diskSizeBytes != 0 && res >= diskSizeBytes/this.SectorSize {
+		return diskSizeBytes/this.SectorSize - 1
+	}
+	return res
+}
+
+// WastedLeadingSectors - count of sectors between end of primary partitions array and FirstUsableLBA.
+func (this Table) WastedLeadingSectors() uint64 {
+	minFirstUsable := this.minFirstUsableLBA()
+	if this.Header.FirstUsableLBA <= minFirstUsable {
+		return 0
+	}
+	return this.Header.FirstUsableLBA - minFirstUsable
+}
+
+// TightenFirstUsable - set FirstUsableLBA right after primary partitions array.
+func (this *Table) TightenFirstUsable() error {
+	return this.setFirstUsableLBA(this.minFirstUsableLBA())
+}
+
+// ReserveLeadingSpace - move FirstUsableLBA forward by sectors, for example for boot loader before first partition.
+func (this *Table) ReserveLeadingSpace(sectors uint64) error {
+	lba := this.Header.FirstUsableLBA + sectors
+	if lba < sectors {
+		return fmt.Errorf("First usable LBA overflow")
+	}
+	return this.setFirstUsableLBA(lba)
+}
+
+// UseModernAlignment - set FirstUsableLBA to 1MiB (LBA 2048 for 512 bytes sectors) as modern tools do.
+// Sector size must be divisor of 1MiB.
+func (this *Table) UseModernAlignment() error {
+	if this.SectorSize == 0 || defaultAlignmentBytes%this.SectorSize != 0 {
+		return fmt.Errorf("Sector size %v isn't divisor of %v bytes alignment", this.SectorSize, defaultAlignmentBytes)
+	}
+	return this.setFirstUsableLBA(defaultAlignmentBytes / this.SectorSize)
+}
+
+// First sector after primary header and partitions array
+func (this Table) minFirstUsableLBA() uint64 {
+	_, primaryEnd, _, _ := this.MetadataSectors()
+	return primaryEnd + 1
+}
+
+// Set FirstUsableLBA, check it doesn't overlap metadata and partitions start after it.
+func (this *Table) setFirstUsableLBA(lba uint64) error {
+	if lba < this.minFirstUsableLBA() {
+		return fmt.Errorf("First usable LBA %v overlaps with primary table, min first usable LBA: %v", lba, this.minFirstUsableLBA())
+	}
+	if lba > this.Header.LastUsableLBA {
+		return fmt.Errorf("First usable LBA %v is after last usable LBA %v", lba, this.Header.LastUsableLBA)
+	}
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && p.FirstLBA < lba {
+			return fmt.Errorf("%w: partition %v starts at %v, new first usable LBA %v", ErrPartitionBeforeUsable, i, p.FirstLBA, lba)
+		}
+	}
+	this.Header.FirstUsableLBA = lba
+	this.Touch()
+	return nil
+}
+
+// FreeRegions - ranges of usable sectors, which aren't used by partitions. Sorted by FirstLBA.
+func (this Table) FreeRegions() []FreeRegion {
+	parts := make([]Partition, 0, len(this.Partitions))
+	for _, p := range this.Partitions {
+		if !p.IsEmpty() && p.FirstLBA <= p.LastLBA {
+			parts = append(parts, p)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].FirstLBA < parts[j].FirstLBA
+	})
+
+	var res []FreeRegion
+	next := this.Header.FirstUsableLBA // first sector, which can be free
+	for _, p := range parts {
+		if next > this.Header.LastUsableLBA {
+			break
+		}
+		if p.FirstLBA > next {
+			last := p.FirstLBA - 1
+			if last > this.Header.LastUsableLBA {
+				last = this.Header.LastUsableLBA
+			}
+			res = append(res, FreeRegion{FirstLBA: next, LastLBA: last})
+		}
+		if p.LastLBA >= next {
+			next = p.LastLBA + 1
+		}
+	}
+	if next <= this.Header.LastUsableLBA {
+		res = append(res, FreeRegion{FirstLBA: next, LastLBA: this.Header.LastUsableLBA})
+	}
+	return res
+}
+
+// AlignedFreeRegions - free regions with start rounded up and end rounded down to alignment boundaries.
+// Regions, which can't hold one aligned unit, are dropped.
+func (this Table) AlignedFreeRegions(alignmentSectors uint64) []FreeRegion {
+	if alignmentSectors == 0 {
+		alignmentSectors = 1
+	}
+	var res []FreeRegion
+	for _, region := range this.FreeRegions() {
+		if aligned, ok := region.aligned(alignmentSectors); ok {
+			res = append(res, aligned)
+		}
+	}
+	return res
+}
+
+// Part of the region, where start and end are aligned to alignmentSectors. False if the part is empty.
+func (this FreeRegion) aligned(alignmentSectors uint64) (FreeRegion, bool) {
+	first := alignUp(this.FirstLBA, alignmentSectors)
+	end := this.LastLBA + 1 // first sector after the region
+	end -= end % alignmentSectors
+	if first < this.FirstLBA || end <= first {
+		return FreeRegion{}, false
+	}
+	return FreeRegion{FirstLBA: first, LastLBA: end - 1}, true
+}
+
+// FreeRegionPlace - place of free region relative to partitions
+type FreeRegionPlace int
+
+const (
+	FreeAtStart   FreeRegionPlace = iota // Between FirstUsableLBA and first partition
+	FreeBetween                          // Between partitions
+	FreeAtEnd                            // Between last partition and LastUsableLBA
+	FreeWholeDisk                        // Whole usable space of disk without partitions
+)
+
+func (this FreeRegionPlace) String() string {
+	switch this {
+	case FreeAtStart:
+		return "FreeAtStart"
+	case FreeBetween:
+		return "FreeBetween"
+	case FreeAtEnd:
+		return "FreeAtEnd"
+	case FreeWholeDisk:
+		return "FreeWholeDisk"
+	default:
+		return fmt.Sprintf("FreeRegionPlace(%d)", int(this))
+	}
+}
+
+// FreeRegionInfo - free region with its place and size, which can be used by 1MiB aligned partitions
+type FreeRegionInfo struct {
+	FreeRegion
+	Place          FreeRegionPlace
+	AlignedSectors uint64
+}
+
+// ClassifyFreeRegions - FreeRegions with their places and aligned sizes.
+func (this Table) ClassifyFreeRegions() []FreeRegionInfo {
+	regions := this.FreeRegions()
+	res := make([]FreeRegionInfo, len(regions))
+	for i, region := range regions {
+		atStart := region.FirstLBA == this.Header.FirstUsableLBA
+		atEnd := region.LastLBA == this.Header.LastUsableLBA
+		res[i].FreeRegion = region
+		switch {
+		case atStart && atEnd:
+			res[i].Place = FreeWholeDisk
+		case atStart:
+			res[i].Place = FreeAtStart
+		case atEnd:
+			res[i].Place = FreeAtEnd
+		default:
+			res[i].Place = FreeBetween
+		}
+		if aligned, ok := region.aligned(defaultAlignmentSectors(this.SectorSize)); ok {
+			res[i].AlignedSectors = aligned.Sectors()
+		}
+	}
+	return res
+}
+
+// FreeSectors - count of usable sectors, which aren't used by partitions.
+func (this Table) FreeSectors() (res uint64) {
+	for _, region := range this.FreeRegions() {
+		res += region.Sectors()
+	}
+	return res
+}
+
+// PartitionsInRange - indexes of non-empty partitions, which have sectors in range [firstLBA, lastLBA].
+func (this Table) PartitionsInRange(firstLBA, lastLBA uint64) []int {
+	var res []int
+	sectors := Partition{FirstLBA: firstLBA, LastLBA: lastLBA}
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && p.overlaps(sectors) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// AllocatableBytes - size of free space, which can be given to partitions aligned to alignmentSectors:
+// sum of AlignedFreeRegions sizes. It is less then free space because of alignment padding.
+func (this Table) AllocatableBytes(alignmentSectors uint64) (res uint64) {
+	for _, region := range this.AlignedFreeRegions(alignmentSectors) {
+		res += region.Sectors() * this.SectorSize
+	}
+	return res
+}
+
+// FreeSpaceFragmentation - 1 - largest free region / all free space. It is 0 for single free region or without free
+// space and goes to 1 for many small free regions.
+func (this Table) FreeSpaceFragmentation() float64 {
+	var total, largest uint64
+	for _, region := range this.FreeRegions() {
+		total += region.Sectors()
+		if region.Sectors() > largest {
+			largest = region.Sectors()
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 1 - float64(largest)/float64(total)
+}
+
+// IsFull - true if free space less then one alignment unit (1MiB), so no new aligned partition can be created.
+func (this Table) IsFull() bool {
+	return this.FreeSectors()*this.SectorSize < defaultAlignmentBytes
+}
+
+// UtilizationPercent - percent of usable sectors, which are used by partitions.
+func (this Table) UtilizationPercent() float64 {
+	if this.Header.LastUsableLBA < this.Header.FirstUsableLBA {
+		return 0
+	}
+	usable := this.Header.LastUsableLBA - this.Header.FirstUsableLBA + 1
+	return float64(usable-this.FreeSectors()) * 100 / float64(usable)
+}
+
+// AreContiguous - true if partitions i and j are adjacent on disk: one of them starts right after end of other.
+// False for empty partitions or bad indexes.
+func (this Table) AreContiguous(i, j int) bool {
+	if i == j || this.checkNonEmptyPartition(i) != nil || this.checkNonEmptyPartition(j) != nil {
+		return false
+	}
+	a, b := this.Partitions[i], this.Partitions[j]
+	return a.LastLBA+1 == b.FirstLBA || b.LastLBA+1 == a.FirstLBA
+}
+
+// IsPhysicallyAligned - true if start of the partition is aligned to physical sector size of the disk,
+// which can be more then logical sector size (512e disks have 4096 bytes physical sectors).
+// False for empty partition or bad index.
+func (this Table) IsPhysicallyAligned(index int, physicalSectorSize uint64) bool {
+	if this.checkNonEmptyPartition(index) != nil || physicalSectorSize == 0 {
+		return false
+	}
+	return this.Partitions[index].FirstLBA*this.SectorSize%physicalSectorSize == 0
+}
+
+// NextAlignedStart - aligned LBA after last used partition (or FirstUsableLBA for table without partitions)
+// for append new partition. False if the LBA is after LastUsableLBA. Zero alignment mean no alignment.
+func (this Table) NextAlignedStart(alignmentSectors uint64) (uint64, bool) {
+	next := this.Header.FirstUsableLBA
+	for _, p := range this.Partitions {
+		if !p.IsEmpty() && p.LastLBA >= next {
+			next = p.LastLBA + 1
+		}
+	}
+	res := alignUp(next, alignmentSectors)
+	if res < next || res > this.Header.LastUsableLBA {
+		return 0, false
+	}
+	return res, true
+}
+
+// Round lba up to multiple of alignment. Zero alignment mean no alignment.
+func alignUp(lba, alignment uint64) uint64 {
+	if alignment == 0 || lba%alignment == 0 {
+		return lba
+	}
+	return lba - lba%alignment + alignment
+}
+
+// FormatBytes - human readable size in binary units: "512 B", "1.0 KiB", "1.46 TiB", "512.0 MiB".
+// Values less then 10 units have up to two decimals, other values have one decimal.
+func FormatBytes(b uint64) string {
+	const units = "KMGTPE"
+	if b < 1024 {
+		return fmt.Sprintf("%v B", b)
+	}
+	value := float64(b)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	res := fmt.Sprintf("%.1f", value)
+	if value < 10 {
+		if precise := fmt.Sprintf("%.2f", value); !strings.HasSuffix(precise, "0") {
+			res = precise
+		}
+	}
+	return res + " " + units[unit:unit+1] + "iB"
+}
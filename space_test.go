@@ -0,0 +1,350 @@
+package gpt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataSectors(t *testing.T) {
+	table := readTestTable(t)
+	lastLBA := uint64(testDiskSize/512 - 1)
+
+	primaryStart, primaryEnd, backupStart, backupEnd := table.MetadataSectors()
+	if primaryStart != 1 || primaryEnd != 33 || backupStart != lastLBA-32 || backupEnd != lastLBA {
+		t.Error(primaryStart, primaryEnd, backupStart, backupEnd)
+	}
+
+	primaryStart, primaryEnd, backupStart, backupEnd = table.CreateOtherSideTable().MetadataSectors()
+	if primaryStart != 1 || primaryEnd != 33 || backupStart != lastLBA-32 || backupEnd != lastLBA {
+		t.Error("Backup: ", primaryStart, primaryEnd, backupStart, backupEnd)
+	}
+
+	primaryStart, primaryEnd, backupStart, backupEnd = NewTable(4096*1024*1024, &NewTableArgs{SectorSize: 4096}).MetadataSectors()
+	if primaryStart != 1 || primaryEnd != 5 || backupStart != 1024*1024-5 || backupEnd != 1024*1024-1 {
+		t.Error("4K: ", primaryStart, primaryEnd, backupStart, backupEnd)
+	}
+}
+
+func TestLastMetadataLBA(t *testing.T) {
+	table := readTestTable(t)
+	if res := table.LastMetadataLBA(testDiskSize); res != 1953525167 {
+		t.Error(res)
+	}
+	if res := table.CreateOtherSideTable().LastMetadataLBA(0); res != 1953525167 {
+		t.Error(res)
+	}
+	if res := table.LastMetadataLBA(testDiskSize * 2); res != 1953525167 {
+		t.Error(res)
+	}
+	if res := table.LastMetadataLBA(1024 * 512); res != 1023 {
+		t.Error(res)
+	}
+}
+
+func TestTightenFirstUsable(t *testing.T) {
+	table := readTestTable(t)
+	if table.WastedLeadingSectors() != 0 {
+		t.Error(table.WastedLeadingSectors())
+	}
+
+	table.Header.FirstUsableLBA = 2048
+	table.Touch()
+	if table.WastedLeadingSectors() != 2048-34 {
+		t.Error(table.WastedLeadingSectors())
+	}
+	if err := table.TightenFirstUsable(); err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.FirstUsableLBA != 34 || table.WastedLeadingSectors() != 0 {
+		t.Error(table.Header.FirstUsableLBA)
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReserveLeadingSpace(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	if err := table.ReserveLeadingSpace(2048); err != nil {
+		t.Fatal(err)
+	}
+	if table.Header.FirstUsableLBA != 34+2048 {
+		t.Error(table.Header.FirstUsableLBA)
+	}
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	table = readTestTable(t)
+	if err := table.ReserveLeadingSpace(2048); !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(err)
+	}
+	if table.Header.FirstUsableLBA != 34 {
+		t.Error("Changed on error: ", table.Header.FirstUsableLBA)
+	}
+}
+
+func TestUseModernAlignment(t *testing.T) {
+	table := NewTable(1024*1024*10, nil)
+	if err := table.UseModernAlignment(); err != nil || table.Header.FirstUsableLBA != 2048 {
+		t.Error(err, table.Header.FirstUsableLBA)
+	}
+	table = NewTable(1024*1024*10, &NewTableArgs{SectorSize: 4096})
+	if err := table.UseModernAlignment(); err != nil || table.Header.FirstUsableLBA != 256 {
+		t.Error(err, table.Header.FirstUsableLBA)
+	}
+
+	table = readTestTable(t)
+	table.Partitions[0].FirstLBA = 2047
+	if err := table.UseModernAlignment(); !errors.Is(err, ErrPartitionBeforeUsable) {
+		t.Error(err)
+	}
+
+	for _, sectorSize := range []uint64{0, 2 * 1024 * 1024} {
+		table = NewTable(1024*1024*10, nil)
+		table.SectorSize = sectorSize
+		if err := table.UseModernAlignment(); err == nil || table.Header.FirstUsableLBA != 34 {
+			t.Error(sectorSize, err)
+		}
+	}
+}
+
+func TestFreeRegions(t *testing.T) {
+	table := readTestTable(t)
+	if regions := table.FreeRegions(); !reflect.DeepEqual(regions, []FreeRegion{{34, 2047}}) {
+		t.Error(regions)
+	}
+	if table.FreeSectors() != 2014 {
+		t.Error(table.FreeSectors())
+	}
+
+	table.RemovePartition(1)
+	table.Partitions[2].LastLBA -= 10
+	if regions := table.FreeRegions(); !reflect.DeepEqual(regions, []FreeRegion{{34, 2047}, {780288, 80781311}, {1953525125, 1953525134}}) {
+		t.Error(regions)
+	}
+
+	empty := NewTable(1024*1024*10, nil)
+	if regions := empty.FreeRegions(); !reflect.DeepEqual(regions, []FreeRegion{{34, 1024*1024*10/512 - 34}}) {
+		t.Error(regions)
+	}
+}
+
+func TestAlignedFreeRegions(t *testing.T) {
+	table := readTestTable(t)
+	table.RemovePartition(1)
+	table.Partitions[2].FirstLBA += 100
+
+	// [34-2047] is too small, [780288-80781411] shrinks
+	regions := table.AlignedFreeRegions(2048)
+	if !reflect.DeepEqual(regions, []FreeRegion{{780288, 80781311}}) {
+		t.Error(regions)
+	}
+
+	regions = table.AlignedFreeRegions(4096)
+	if !reflect.DeepEqual(regions, []FreeRegion{{782336, 80781311}}) {
+		t.Error(regions)
+	}
+
+	if regions = table.AlignedFreeRegions(0); !reflect.DeepEqual(regions, table.FreeRegions()) {
+		t.Error(regions)
+	}
+}
+
+func TestPartitionsInRange(t *testing.T) {
+	table := readTestTable(t)
+	if res := table.PartitionsInRange(780000, 780300); !reflect.DeepEqual(res, []int{0, 1}) {
+		t.Error(res)
+	}
+	if res := table.PartitionsInRange(80781312, 80781312); !reflect.DeepEqual(res, []int{2}) {
+		t.Error(res)
+	}
+	if res := table.PartitionsInRange(0, 2047); res != nil {
+		t.Error(res)
+	}
+}
+
+func TestClassifyFreeRegions(t *testing.T) {
+	table := readTestTable(t)
+	table.RemovePartition(1)
+	table.Partitions[2].LastLBA -= 4096
+	table.Touch()
+
+	expected := []FreeRegionInfo{
+		{FreeRegion{34, 2047}, FreeAtStart, 0},
+		{FreeRegion{780288, 80781311}, FreeBetween, 80001024},
+		{FreeRegion{1953521039, 1953525134}, FreeAtEnd, 2048},
+	}
+	if res := table.ClassifyFreeRegions(); !reflect.DeepEqual(res, expected) {
+		t.Error(res)
+	}
+	if FreeAtEnd.String() != "FreeAtEnd" {
+		t.Error(FreeAtEnd.String())
+	}
+
+	table = NewTable(1024*1024*10, nil)
+	if res := table.ClassifyFreeRegions(); len(res) != 1 || res[0].Place != FreeWholeDisk {
+		t.Error(res)
+	}
+
+	// Sectors more then alignment
+	for _, sectorSize := range []uint64{0, 2 * 1024 * 1024} {
+		table.SectorSize = sectorSize
+		if res := table.ClassifyFreeRegions(); len(res) != 1 || res[0].AlignedSectors != res[0].Sectors() {
+			t.Error(sectorSize, res)
+		}
+	}
+}
+
+func TestAllocatableBytes(t *testing.T) {
+	table := readTestTable(t)
+	table.RemovePartition(1)
+	table.Partitions[2].FirstLBA += 100
+
+	if res := table.AllocatableBytes(2048); res != 80001024*512 {
+		t.Error(res)
+	}
+	if res := table.AllocatableBytes(0); res != table.FreeSectors()*512 {
+		t.Error(res)
+	}
+	if table.AllocatableBytes(2048) >= table.FreeSectors()*512 {
+		t.Error("Alignment doesn't reduce allocatable space")
+	}
+}
+
+func TestRecommendedAlignment(t *testing.T) {
+	if res := RecommendedAlignment(GUID_EFI_SYSTEM, 512); res != 2048 {
+		t.Error("ESP 512: ", res)
+	}
+	if res := RecommendedAlignment(GUID_LINUX_FS, 4096); res != 256 {
+		t.Error("Linux 4096: ", res)
+	}
+	if res := RecommendedAlignment(GUID_MSR, 512); res != 8 {
+		t.Error("MSR 512: ", res)
+	}
+	if res := RecommendedAlignment(GUID_MSR, 4096); res != 1 {
+		t.Error("MSR 4096: ", res)
+	}
+}
+
+func TestFreeSpaceFragmentation(t *testing.T) {
+	table := NewTable(1024*1024*100, nil)
+	if res := table.FreeSpaceFragmentation(); res != 0 {
+		t.Error("Empty disk: ", res)
+	}
+
+	// Partitions of 1MiB with 1MiB gaps after first 10MiB, last partition fill end of the disk
+	for i := 0; i < 20; i++ {
+		first := uint64(20480 + i*4096)
+		table.Partitions[i] = Partition{Type: GUID_LINUX_FS, FirstLBA: first, LastLBA: first + 2047}
+	}
+	table.Partitions[19].LastLBA = table.Header.LastUsableLBA
+	res := table.FreeSpaceFragmentation()
+	if res < 0.5 || res >= 1 {
+		t.Error("Fragmented: ", res)
+	}
+
+	// Single free region [34-2047]
+	table = readTestTable(t)
+	if res = table.FreeSpaceFragmentation(); res != 0 {
+		t.Error("Fixture: ", res)
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	table := readTestTable(t)
+	if !table.IsFull() {
+		t.Error("Fixture is full")
+	}
+	if percent := table.UtilizationPercent(); percent < 99.99 || percent >= 100 {
+		t.Error(percent)
+	}
+
+	table.Partitions[0].FirstLBA = table.Header.FirstUsableLBA
+	if !table.IsFull() || table.UtilizationPercent() != 100 {
+		t.Error(table.UtilizationPercent())
+	}
+
+	table.RemovePartition(1)
+	if table.IsFull() {
+		t.Error("Free partition")
+	}
+	if percent := table.UtilizationPercent(); percent < 95 || percent > 96 {
+		t.Error(percent)
+	}
+
+	empty := NewTable(1024*1024*10, nil)
+	if empty.IsFull() || empty.UtilizationPercent() != 0 {
+		t.Error(empty.UtilizationPercent())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:                                       "0 B",
+		1023:                                    "1023 B",
+		1024:                                    "1.0 KiB",
+		1536:                                    "1.5 KiB",
+		512 * 1024 * 1024:                       "512.0 MiB",
+		1610612736000:                           "1.46 TiB",
+		testDiskSize:                            "931.5 GiB",
+		1024 * 1024 * 1024 * 1024 * 1024 * 1024: "1.0 EiB",
+		^uint64(0):                              "16.0 EiB",
+	}
+	for b, expected := range tests {
+		if res := FormatBytes(b); res != expected {
+			t.Errorf("%v: %v, expected %v", b, res, expected)
+		}
+	}
+}
+
+func TestNextAlignedStart(t *testing.T) {
+	table := NewTable(1024*1024*100, nil)
+	if res, ok := table.NextAlignedStart(2048); !ok || res != 2048 {
+		t.Error("Empty: ", res, ok)
+	}
+	if res, ok := table.NextAlignedStart(0); !ok || res != 34 {
+		t.Error("Empty without alignment: ", res, ok)
+	}
+
+	if _, err := table.AddPartition(GUID_EFI_SYSTEM, 1024*1024*10+1, 2048, "esp"); err != nil {
+		t.Fatal(err)
+	}
+	if res, ok := table.NextAlignedStart(2048); !ok || res != 2048+20480+2048 {
+		t.Error("One partition: ", res, ok)
+	}
+
+	table = readTestTable(t)
+	if res, ok := table.NextAlignedStart(1); ok {
+		t.Error("Full disk: ", res)
+	}
+}
+
+func TestIsPhysicallyAligned(t *testing.T) {
+	table := readTestTable(t)
+	if !table.IsPhysicallyAligned(0, 4096) {
+		t.Error("Start 2048")
+	}
+	table.Partitions[0].FirstLBA = 63
+	if table.IsPhysicallyAligned(0, 4096) || !table.IsPhysicallyAligned(0, 512) {
+		t.Error("Start 63")
+	}
+	if table.IsPhysicallyAligned(3, 4096) || table.IsPhysicallyAligned(-1, 4096) {
+		t.Error("Empty partition")
+	}
+}
+
+func TestAreContiguous(t *testing.T) {
+	table := readTestTable(t)
+	if !table.AreContiguous(0, 1) || !table.AreContiguous(2, 1) {
+		t.Error("Adjacent partitions")
+	}
+	if table.AreContiguous(0, 2) || table.AreContiguous(1, 1) || table.AreContiguous(0, 3) || table.AreContiguous(0, -1) {
+		t.Error("Not adjacent partitions")
+	}
+	table.Partitions[1].FirstLBA++
+	if table.AreContiguous(0, 1) {
+		t.Error("Gap between partitions")
+	}
+}
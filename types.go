@@ -0,0 +1,113 @@
+package gpt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownPartType = errors.New("Unknown partition type")
+)
+
+// Known partition types
+var (
+	GUID_LVM        = PartType([16]byte{0x79, 0xd3, 0xd6, 0xe6, 0x7, 0xf5, 0xc2, 0x44, 0xa2, 0x3c, 0x23, 0x8f, 0x2a, 0x3d, 0xf9, 0x28})  // E6D6D379-F507-44C2-A23C-238F2A3DF928
+	GUID_BIOS_BOOT  = PartType([16]byte{0x48, 0x61, 0x68, 0x21, 0x49, 0x64, 0x6f, 0x6e, 0x74, 0x4e, 0x65, 0x65, 0x64, 0x45, 0x46, 0x49}) // 21686148-6449-6E6F-744E-656564454649
+	GUID_LINUX_FS   = PartType([16]byte{0xaf, 0x3d, 0xc6, 0xf, 0x83, 0x84, 0x72, 0x47, 0x8e, 0x79, 0x3d, 0x69, 0xd8, 0x47, 0x7d, 0xe4})  // 0FC63DAF-8483-4772-8E79-3D69D8477DE4
+	GUID_EFI_SYSTEM = PartType([16]byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0x0, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b})  // C12A7328-F81F-11D2-BA4B-00A0C93EC93B
+	GUID_MSR        = PartType([16]byte{0x16, 0xe3, 0xc9, 0xe3, 0x5c, 0xb, 0xb8, 0x4d, 0x81, 0x7d, 0xf9, 0x2d, 0xf0, 0x2, 0x15, 0xae})   // E3C9E316-0B5C-4DB8-817D-F92DF00215AE
+)
+
+// Registry of known partition types with human readable names
+var partTypeNames = map[PartType]string{
+	GUID_LVM:        "Linux LVM",
+	GUID_BIOS_BOOT:  "BIOS boot",
+	GUID_LINUX_FS:   "Linux filesystem",
+	GUID_EFI_SYSTEM: "EFI System",
+	GUID_MSR:        "Microsoft reserved",
+}
+
+// Partition types without user data
+var reservedPartTypes = map[PartType]bool{
+	GUID_BIOS_BOOT:  true,
+	GUID_MSR:        true,
+	GUID_EFI_SYSTEM: true,
+}
+
+// TypeName - human readable name of partition type. Empty string for unknown types.
+func (this Partition) TypeName() string {
+	return partTypeNames[this.Type]
+}
+
+func (this *Partition) SetType(typ PartType) {
+	this.Type = typ
+}
+
+// SetTypeName - set partition type by its name from the registry, for example "Linux filesystem".
+func (this *Partition) SetTypeName(name string) error {
+	for typ, typeName := range partTypeNames {
+		if typeName == name {
+			this.Type = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownPartType, name)
+}
+
+// HasBIOSBoot - true if the table has BIOS boot partition (used by GRUB for boot from GPT disk in legacy BIOS mode).
+func (this Table) HasBIOSBoot() bool {
+	for _, p := range this.Partitions {
+		if p.Type == GUID_BIOS_BOOT {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReserved - true for partition types without user data: BIOS boot, Microsoft reserved and EFI System.
+// EFI System partition is treated as reserved because it contains boot loaders only, which are recreated by OS installer.
+func (this Partition) IsReserved() bool {
+	return reservedPartTypes[this.Type]
+}
+
+// DataPartitions - indexes of non-empty and not reserved partitions (see Partition.IsReserved).
+func (this Table) DataPartitions() []int {
+	var res []int
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && !p.IsReserved() {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// IsMSR - true for Microsoft reserved partition. Windows use it and it must be kept on disk.
+func (this Partition) IsMSR() bool {
+	return this.Type == GUID_MSR
+}
+
+// FindMSR - index of first Microsoft reserved partition.
+func (this Table) FindMSR() (int, bool) {
+	for i, p := range this.Partitions {
+		if p.IsMSR() {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// FindBootPartition - index of partition, which most likely used for boot. First EFI System partition is preferred,
+// else first partition with LegacyBIOSBootable attribute. Return false if no such partition.
+func (this Table) FindBootPartition() (int, bool) {
+	for i, p := range this.Partitions {
+		if p.Type == GUID_EFI_SYSTEM {
+			return i, true
+		}
+	}
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && p.Attributes().LegacyBIOSBootable() {
+			return i, true
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,102 @@
+package gpt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHasBIOSBoot(t *testing.T) {
+	table := readTestTable(t)
+	if table.HasBIOSBoot() {
+		t.Error("Fixture hasn't BIOS boot partition")
+	}
+
+	table.Partitions[3] = Partition{Type: GUID_BIOS_BOOT, FirstLBA: 34, LastLBA: 2047}
+	if !table.HasBIOSBoot() {
+		t.Error("BIOS boot partition not found")
+	}
+	if GUID_BIOS_BOOT.String() != "21686148-6449-6E6F-744E-656564454649" {
+		t.Error(GUID_BIOS_BOOT.String())
+	}
+	if partTypeNames[GUID_BIOS_BOOT] == "" {
+		t.Error("BIOS boot isn't registered")
+	}
+}
+
+func TestPartitionSetType(t *testing.T) {
+	var p Partition
+	if p.TypeName() != "" {
+		t.Error(p.TypeName())
+	}
+
+	if err := p.SetTypeName("Linux filesystem"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != GUID_LINUX_FS || p.TypeName() != "Linux filesystem" {
+		t.Error(p.Type, p.TypeName())
+	}
+
+	p.SetType(GUID_LVM)
+	if p.TypeName() != "Linux LVM" {
+		t.Error(p.TypeName())
+	}
+
+	if err := p.SetTypeName("Unknown fs"); !errors.Is(err, ErrUnknownPartType) {
+		t.Error(err)
+	}
+	if p.Type != GUID_LVM {
+		t.Error("Type changed on error")
+	}
+}
+
+func TestDataPartitions(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1] = Partition{Type: GUID_MSR, FirstLBA: 780288, LastLBA: 1042431}
+	table.Partitions[2].FirstLBA = 1042432
+
+	if !table.Partitions[0].IsReserved() || !table.Partitions[1].IsReserved() || table.Partitions[2].IsReserved() {
+		t.Error("Bad reserved types")
+	}
+	if table.Partitions[0].TypeName() != "EFI System" || table.Partitions[1].TypeName() != "Microsoft reserved" {
+		t.Error(table.Partitions[0].TypeName(), table.Partitions[1].TypeName())
+	}
+	if res := table.DataPartitions(); len(res) != 1 || res[0] != 2 {
+		t.Error(res)
+	}
+}
+
+func TestFindBootPartition(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[1].SetAttributes(Attributes(0).With(FlagLegacyBIOSBootable, true))
+	if index, ok := table.FindBootPartition(); !ok || index != 0 {
+		t.Error(index, ok)
+	}
+
+	table.Partitions[0].SetType(GUID_LINUX_FS)
+	if index, ok := table.FindBootPartition(); !ok || index != 1 {
+		t.Error(index, ok)
+	}
+
+	table.Partitions[1].SetAttributes(0)
+	if index, ok := table.FindBootPartition(); ok || index != -1 {
+		t.Error(index, ok)
+	}
+}
+
+func TestFindMSR(t *testing.T) {
+	table := readTestTable(t)
+	if index, ok := table.FindMSR(); ok || index != -1 {
+		t.Error(index, ok)
+	}
+
+	table.Partitions[5] = Partition{Type: GUID_MSR, FirstLBA: 34, LastLBA: 2047}
+	if !table.Partitions[5].IsMSR() || table.Partitions[0].IsMSR() {
+		t.Error("IsMSR")
+	}
+	if index, ok := table.FindMSR(); !ok || index != 5 {
+		t.Error(index, ok)
+	}
+	if GUID_MSR.String() != "E3C9E316-0B5C-4DB8-817D-F92DF00215AE" {
+		t.Error(GUID_MSR.String())
+	}
+}
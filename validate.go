@@ -0,0 +1,83 @@
+package gpt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrDegeneratePartition     = errors.New("Partition has LastLBA less then FirstLBA")
+	ErrPartitionOverlapsBackup = errors.New("Partition overlaps backup GPT")
+	ErrDuplicatePartitionGUID  = errors.New("Duplicate partition GUID")
+)
+
+// Validate - check the table is consistent and can be written to disk.
+// Return first found problem.
+func (this Table) Validate() error {
+	if string(this.Header.Signature[:]) != gptSignature {
+		return ErrBadSignature
+	}
+	if this.Header.calcCRC() != this.Header.CRC {
+		return ErrBadHeaderCRC
+	}
+	if this.calcPartitionsCRC() != this.Header.PartitionsCRC {
+		return ErrBadPartitionsCRC
+	}
+	if degenerate := this.DegeneratePartitions(); len(degenerate) > 0 {
+		return fmt.Errorf("%w: partitions %v", ErrDegeneratePartition, degenerate)
+	}
+	if err := this.checkWritable(); err != nil {
+		return err
+	}
+	if overlapped := this.partitionsOverlappingBackup(); len(overlapped) > 0 {
+		return fmt.Errorf("%w: partitions %v", ErrPartitionOverlapsBackup, overlapped)
+	}
+	if pairs := this.overlappedPartitions(); len(pairs) > 0 {
+		return fmt.Errorf("%w: %v and %v", ErrPartitionOverlap, pairs[0][0], pairs[0][1])
+	}
+	if duplicates := this.duplicatePartitionGUIDs(); len(duplicates) > 0 {
+		return fmt.Errorf("%w: %v partitions %v", ErrDuplicatePartitionGUID, this.Partitions[duplicates[0]].Id, duplicates)
+	}
+	return nil
+}
+
+// DegeneratePartitions - indexes of non-empty partitions with LastLBA < FirstLBA.
+func (this Table) DegeneratePartitions() (res []int) {
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && p.LastLBA < p.FirstLBA {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// Indexes of non-empty partitions, which overlap backup partitions array or backup header.
+// Place of backup is taken from the header (see MetadataSectors), so it finds partitions inside usable range
+// when LastUsableLBA is broken.
+func (this Table) partitionsOverlappingBackup() (res []int) {
+	_, _, backupStart, backupEnd := this.MetadataSectors()
+	for i, p := range this.Partitions {
+		if !p.IsEmpty() && p.FirstLBA <= backupEnd && p.LastLBA >= backupStart {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// Indexes of partitions with first non-zero partition guid, which is used more then once.
+func (this Table) duplicatePartitionGUIDs() []int {
+	var emptyGuid Guid
+	indexes := make(map[Guid][]int)
+	for i, p := range this.Partitions {
+		if p.IsEmpty() || p.Id == emptyGuid {
+			continue
+		}
+		indexes[p.Id] = append(indexes[p.Id], i)
+	}
+	for i, p := range this.Partitions {
+		if len(indexes[p.Id]) > 1 && indexes[p.Id][0] == i {
+			return indexes[p.Id]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package gpt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	table := readTestTable(t)
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	table.Partitions[1].LastLBA = table.Partitions[2].FirstLBA
+	if err := table.Validate(); !errors.Is(err, ErrBadPartitionsCRC) {
+		t.Error(err)
+	}
+	table.Touch()
+	if err := table.Validate(); !errors.Is(err, ErrPartitionOverlap) {
+		t.Error(err)
+	}
+
+	table.Partitions[1].LastLBA = table.Partitions[2].FirstLBA - 1
+	table.Touch()
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	table.Header.Revision++
+	if err := table.Validate(); !errors.Is(err, ErrBadHeaderCRC) {
+		t.Error(err)
+	}
+
+	table.Header.Signature[0] = 0
+	if err := table.Validate(); !errors.Is(err, ErrBadSignature) {
+		t.Error(err)
+	}
+}
+
+func TestDegeneratePartitions(t *testing.T) {
+	table := readTestTable(t)
+	if degenerate := table.DegeneratePartitions(); len(degenerate) != 0 {
+		t.Error(degenerate)
+	}
+
+	table.Partitions[1].LastLBA = table.Partitions[1].FirstLBA - 1
+	table.Partitions[5].FirstLBA = 10 // empty partition
+	table.Touch()
+	if degenerate := table.DegeneratePartitions(); len(degenerate) != 1 || degenerate[0] != 1 {
+		t.Error(degenerate)
+	}
+	if err := table.Validate(); !errors.Is(err, ErrDegeneratePartition) {
+		t.Error(err)
+	}
+}
+
+func TestValidateOverlapsBackup(t *testing.T) {
+	table := readTestTable(t)
+	table.Header.LastUsableLBA = table.Header.HeaderCopyStartLBA - 1
+	table.Partitions[2].LastLBA = table.Header.LastUsableLBA
+	table.Touch()
+	if err := table.Validate(); !errors.Is(err, ErrPartitionOverlapsBackup) {
+		t.Error(err)
+	}
+
+	table.Header.LastUsableLBA -= 32
+	table.Partitions[2].LastLBA = table.Header.LastUsableLBA
+	table.Touch()
+	if err := table.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateDuplicatePartitionGUID(t *testing.T) {
+	table := readTestTable(t)
+	table.Partitions[2].Id = table.Partitions[0].Id
+	table.Touch()
+	err := table.Validate()
+	if !errors.Is(err, ErrDuplicatePartitionGUID) || !strings.Contains(err.Error(), "[0 2]") {
+		t.Error(err)
+	}
+
+	var emptyGuid Guid
+	table.Partitions[0].Id, table.Partitions[2].Id = emptyGuid, emptyGuid
+	table.Touch()
+	if err = table.Validate(); err != nil {
+		t.Error(err)
+	}
+}